@@ -0,0 +1,165 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// Action is a typed build action, decoded by its _class.
+type Action interface {
+	Class() string
+}
+
+// ActionBase holds the class every action has.
+type ActionBase struct {
+	ClassName string `json:"_class"`
+}
+
+// Class returns the Java class of the action.
+func (a *ActionBase) Class() string { return a.ClassName }
+
+// GitBuildDataAction is the git plugin's record of the revisions built.
+type GitBuildDataAction struct {
+	ActionBase
+	BuildsByBranchName map[string]Builds `json:"buildsByBranchName"`
+	LastBuiltRevision  BuildRevision     `json:"lastBuiltRevision"`
+	RemoteUrls         []string          `json:"remoteUrls"`
+	ScmName            string            `json:"scmName"`
+}
+
+// ParametersAction holds the parameters the build was started with.
+type ParametersAction struct {
+	ActionBase
+	Parameters []parameter `json:"parameters"`
+}
+
+// CauseAction holds the causes of the build.
+type CauseAction struct {
+	ActionBase
+	Causes []Cause `json:"-"`
+}
+
+// UnmarshalJSON decodes the causes into typed causes.
+func (a *CauseAction) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ActionBase
+		Causes []map[string]interface{} `json:"causes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	a.ActionBase = raw.ActionBase
+	a.Causes = ParseCauses(raw.Causes)
+	return nil
+}
+
+// TestResultAction summarizes the JUnit test results of the build.
+type TestResultAction struct {
+	ActionBase
+	FailCount  int64  `json:"failCount"`
+	SkipCount  int64  `json:"skipCount"`
+	TotalCount int64  `json:"totalCount"`
+	UrlName    string `json:"urlName"`
+}
+
+// InputAction is present on pipeline runs that have used the input step.
+// Use PipelineRun.GetPendingInputActions for the inputs still waiting.
+type InputAction struct {
+	ActionBase
+}
+
+// EnvInjectAction holds the variables injected by the EnvInject plugin.
+type EnvInjectAction struct {
+	ActionBase
+	EnvMap map[string]string `json:"envMap"`
+}
+
+// RawAction is an action of a class without a registered type.
+type RawAction struct {
+	ActionBase
+	Raw json.RawMessage
+}
+
+var (
+	actionTypesMu sync.RWMutex
+	actionTypes   = map[string]func() Action{
+		"hudson.plugins.git.util.BuildData":                              func() Action { return new(GitBuildDataAction) },
+		"hudson.model.ParametersAction":                                  func() Action { return new(ParametersAction) },
+		"hudson.model.CauseAction":                                       func() Action { return new(CauseAction) },
+		"hudson.tasks.junit.TestResultAction":                            func() Action { return new(TestResultAction) },
+		"org.jenkinsci.plugins.workflow.support.steps.input.InputAction": func() Action { return new(InputAction) },
+		"org.jenkinsci.plugins.envinject.EnvInjectPluginAction":          func() Action { return new(EnvInjectAction) },
+	}
+)
+
+// RegisterActionType registers the Go type actions of a class are decoded
+// into. The factory must return a new pointer that encoding/json can decode
+// into. Registering a class again replaces the previous type.
+func RegisterActionType(class string, factory func() Action) {
+	actionTypesMu.Lock()
+	defer actionTypesMu.Unlock()
+	actionTypes[class] = factory
+}
+
+// ParseAction decodes a single action by its _class. Actions of unknown
+// classes, or that fail to decode, are returned as *RawAction.
+func ParseAction(data json.RawMessage) Action {
+	var base ActionBase
+	_ = json.Unmarshal(data, &base)
+	fallback := &RawAction{ActionBase: base, Raw: data}
+
+	actionTypesMu.RLock()
+	factory, ok := actionTypes[base.ClassName]
+	actionTypesMu.RUnlock()
+	if !ok {
+		return fallback
+	}
+	action := factory()
+	if err := json.Unmarshal(data, action); err != nil {
+		return fallback
+	}
+	return action
+}
+
+// UnmarshalJSON decodes the action and keeps its JSON for GetTypedActions.
+func (g *generalObj) UnmarshalJSON(data []byte) error {
+	type plain generalObj
+	if err := json.Unmarshal(data, (*plain)(g)); err != nil {
+		return err
+	}
+	g.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// GetTypedActions returns the actions of the build decoded into their
+// registered types; use a type switch to pick the ones of interest.
+// Actions without any data, which Jenkins reports as {} or null, are left out.
+func (b *Build) GetTypedActions() []Action {
+	actions := make([]Action, 0, len(b.Raw.Actions))
+	for _, a := range b.Raw.Actions {
+		raw := a.raw
+		if raw == nil {
+			raw, _ = json.Marshal(a)
+		}
+		if trimmed := bytes.TrimSpace(raw); bytes.Equal(trimmed, []byte("{}")) || bytes.Equal(trimmed, []byte("null")) {
+			continue
+		}
+		actions = append(actions, ParseAction(raw))
+	}
+	return actions
+}
@@ -0,0 +1,76 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const buildActions = `{"number": 5, "actions": [
+	{"_class": "hudson.model.CauseAction", "causes": [{"_class": "hudson.model.Cause$UserIdCause", "userId": "alice"}]},
+	{"_class": "hudson.model.ParametersAction", "parameters": [{"name": "ENV", "value": "prod"}]},
+	{},
+	null,
+	{"_class": "hudson.plugins.git.util.BuildData", "lastBuiltRevision": {"SHA1": "abc123"}, "remoteUrls": ["https://example.com/repo.git"]},
+	{"_class": "hudson.tasks.junit.TestResultAction", "failCount": 1, "skipCount": 2, "totalCount": 10, "urlName": "testReport"},
+	{"_class": "org.jenkinsci.plugins.envinject.EnvInjectPluginAction", "envMap": {"FOO": "bar"}},
+	{"_class": "com.example.CoverageAction", "lineRate": 0.87}
+]}`
+
+// coverageAction stands in for a decoder of an in-house plugin.
+type coverageAction struct {
+	ActionBase
+	LineRate float64 `json:"lineRate"`
+}
+
+func TestBuild_GetTypedActions(t *testing.T) {
+	build := &Build{Raw: new(BuildResponse)}
+	require.NoError(t, json.Unmarshal([]byte(buildActions), build.Raw))
+
+	// the untyped view keeps working
+	assert.Equal(t, "alice", build.Raw.Actions[0].Causes[0]["userId"])
+	assert.Equal(t, "abc123", build.Raw.Actions[4].LastBuiltRevision.SHA1)
+
+	actions := build.GetTypedActions()
+	require.Equal(t, 6, len(actions))
+	assert.Equal(t, "alice", actions[0].(*CauseAction).Causes[0].(*UserIdCause).UserID)
+	assert.Equal(t, "prod", actions[1].(*ParametersAction).Parameters[0].Value)
+	assert.Equal(t, "abc123", actions[2].(*GitBuildDataAction).LastBuiltRevision.SHA1)
+	assert.Equal(t, int64(10), actions[3].(*TestResultAction).TotalCount)
+	assert.Equal(t, "bar", actions[4].(*EnvInjectAction).EnvMap["FOO"])
+
+	raw, ok := actions[5].(*RawAction)
+	require.True(t, ok)
+	assert.Equal(t, "com.example.CoverageAction", raw.Class())
+	assert.JSONEq(t, `{"_class": "com.example.CoverageAction", "lineRate": 0.87}`, string(raw.Raw))
+}
+
+func TestRegisterActionType(t *testing.T) {
+	RegisterActionType("com.example.CoverageAction", func() Action { return new(coverageAction) })
+	defer func() {
+		actionTypesMu.Lock()
+		delete(actionTypes, "com.example.CoverageAction")
+		actionTypesMu.Unlock()
+	}()
+
+	action := ParseAction(json.RawMessage(`{"_class": "com.example.CoverageAction", "lineRate": 0.87}`))
+	coverage, ok := action.(*coverageAction)
+	require.True(t, ok)
+	assert.Equal(t, 0.87, coverage.LineRate)
+}
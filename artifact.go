@@ -17,12 +17,103 @@ package gojenkins
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 )
 
+// VerifyMode selects how downloaded artifacts are verified.
+type VerifyMode int
+
+const (
+	// VerifyOff only computes the checksums.
+	VerifyOff VerifyMode = iota
+	// VerifyFingerprint looks the MD5 up in the Jenkins fingerprint database.
+	VerifyFingerprint
+	// VerifySidecar compares the SHA-256 with the one in an artifact of the
+	// same name ending in ".sha256", as written by sha256sum.
+	VerifySidecar
+)
+
+const checksumSidecarSuffix = ".sha256"
+
+// Checksums are the hex encoded digests of a downloaded file.
+type Checksums struct {
+	MD5    string `json:"md5"`
+	SHA256 string `json:"sha256"`
+}
+
+// ErrChecksumMismatch occurs when a download does not match its checksum file.
+type ErrChecksumMismatch struct {
+	File     string
+	Expected string
+	Actual   string
+}
+
+func (e *ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected sha256 %s, got %s", e.File, e.Expected, e.Actual)
+}
+
+// checksummer computes the checksums of everything written to it.
+type checksummer struct {
+	md5    hash.Hash
+	sha256 hash.Hash
+}
+
+func newChecksummer() *checksummer {
+	return &checksummer{md5: md5.New(), sha256: sha256.New()}
+}
+
+func (c *checksummer) Write(p []byte) (int, error) {
+	c.md5.Write(p)
+	return c.sha256.Write(p)
+}
+
+func (c *checksummer) Reset() {
+	c.md5.Reset()
+	c.sha256.Reset()
+}
+
+func (c *checksummer) Sum() Checksums {
+	return Checksums{MD5: hex.EncodeToString(c.md5.Sum(nil)), SHA256: hex.EncodeToString(c.sha256.Sum(nil))}
+}
+
+// fileChecksums computes the checksums of a local file.
+func fileChecksums(path string) (Checksums, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Checksums{}, err
+	}
+	defer func() { _ = f.Close() }()
+	sums := newChecksummer()
+	if _, err := io.Copy(sums, f); err != nil {
+		return Checksums{}, err
+	}
+	return sums.Sum(), nil
+}
+
+// artifactSHA256 downloads an artifact of the build to compute its SHA-256,
+// without writing it to disk.
+func (b *Build) artifactSHA256(ctx context.Context, rel string) (string, error) {
+	sums := newChecksummer()
+	resp, err := b.Jenkins.Requester.Get(ctx, b.Base+"/artifact/"+rel, sums, nil)
+	if err != nil {
+		return "", fmt.Errorf("hashing artifact %s: %w", rel, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("hashing artifact %s: status %d", rel, resp.StatusCode)
+	}
+	return sums.Sum().SHA256, nil
+}
+
 // Represents an Artifact
 type Artifact struct {
 	Jenkins  *Jenkins
@@ -48,28 +139,51 @@ func (a Artifact) GetData(ctx context.Context) ([]byte, error) {
 	return []byte(data), nil
 }
 
-// Save artifact to a specific path, using your own filename.
+// Save artifact to a specific path, using your own filename. The download
+// is verified against the Jenkins fingerprint database.
 func (a Artifact) Save(ctx context.Context, path string) (bool, error) {
-	data, err := a.GetData(ctx)
-
-	if err != nil {
-		return false, errors.New("no data received, not saving file")
+	if _, err := a.Download(ctx, path, VerifyFingerprint); err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
-	if _, err = os.Stat(path); err == nil {
+// Download streams the artifact to path, computing its checksums on the way,
+// and verifies it as requested by mode. The download goes to a temporary file
+// next to path, which only replaces path once verified.
+func (a Artifact) Download(ctx context.Context, path string, mode VerifyMode) (*Checksums, error) {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
 		Warning.Println("Local Copy already exists, Overwriting...")
+		perm = info.Mode().Perm()
 	}
-
-	err = os.WriteFile(path, data, 0644)
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	sums := newChecksummer()
+	response, err := a.Jenkins.Requester.Get(ctx, a.Path, io.MultiWriter(f, sums), nil)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("could not get file contents: status %d", response.StatusCode)
+	}
+	result := sums.Sum()
+	if err == nil {
+		err = a.validateDownload(ctx, result, mode)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
 	}
-
-	_, err = a.validateDownload(ctx, path)
 	if err != nil {
-		return false, err
+		_ = os.Remove(f.Name())
+		return nil, err
 	}
-	return true, nil
+	return &result, nil
 }
 
 // Save Artifact to directory using Artifact filename.
@@ -78,43 +192,42 @@ func (a Artifact) SaveToDir(ctx context.Context, dir string) (bool, error) {
 		Error.Printf("can't save artifact: directory %s does not exist", dir)
 		return false, fmt.Errorf("can't save artifact: directory %s does not exist", dir)
 	}
-	saved, err := a.Save(ctx, path.Join(dir, a.FileName))
-	if err != nil {
-		return saved, nil
-	}
-	return saved, nil
+	return a.Save(ctx, path.Join(dir, a.FileName))
 }
 
-// Compare Remote and local MD5
-func (a Artifact) validateDownload(ctx context.Context, path string) (bool, error) {
-	localHash := a.getMD5local(path)
-
-	fp := FingerPrint{Jenkins: a.Jenkins, Base: "/fingerprint/", Id: localHash, Raw: new(FingerPrintResponse)}
-
-	valid, err := fp.ValidateForBuild(ctx, a.FileName, a.Build)
-
-	if err != nil {
-		return false, err
-	}
-	if !valid {
-		return false, errors.New("fingerprint of the downloaded artifact could not be verified")
-	}
-	return true, nil
-}
-
-// Get Local MD5
-func (a Artifact) getMD5local(path string) string {
-	h := md5.New()
-	localFile, err := os.Open(path)
-	if err != nil {
-		return ""
-	}
-	buffer := make([]byte, 1<<20)
-	defer func() { _ = localFile.Close() }()
-	n, err := localFile.Read(buffer)
-	for err == nil {
-		h.Write(buffer[0:n])
-		n, err = localFile.Read(buffer)
+// validateDownload checks the checksums of a download as requested by mode.
+func (a Artifact) validateDownload(ctx context.Context, sums Checksums, mode VerifyMode) error {
+	switch mode {
+	case VerifyOff:
+		return nil
+	case VerifyFingerprint:
+		fp := FingerPrint{Jenkins: a.Jenkins, Base: "/fingerprint/", Id: sums.MD5, Raw: new(FingerPrintResponse)}
+		valid, err := fp.ValidateForBuild(ctx, a.FileName, a.Build)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("fingerprint of the downloaded artifact could not be verified")
+		}
+		return nil
+	case VerifySidecar:
+		var body string
+		response, err := a.Jenkins.Requester.Get(ctx, a.Path+checksumSidecarSuffix, &body, nil)
+		if err != nil {
+			return err
+		}
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("could not get checksum of %s: status %d", a.FileName, response.StatusCode)
+		}
+		// sha256sum format: the digest, then optionally the file name
+		fields := strings.Fields(body)
+		if len(fields) == 0 {
+			return fmt.Errorf("empty checksum file for %s", a.FileName)
+		}
+		if !strings.EqualFold(fields[0], sums.SHA256) {
+			return &ErrChecksumMismatch{File: a.FileName, Expected: strings.ToLower(fields[0]), Actual: sums.SHA256}
+		}
+		return nil
 	}
-	return fmt.Sprintf("%x", h.Sum(nil))
+	return fmt.Errorf("unknown verify mode %d", mode)
 }
@@ -0,0 +1,433 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// number of artifacts downloaded at once by default
+const artifactWorkers = 4
+
+// suffix of files being downloaded, kept when interrupted to be resumed
+const partialSuffix = ".part"
+
+// ArtifactDownloadOptions configures Build.DownloadArtifacts.
+//
+// Include and Exclude are glob patterns: "*" and "?" match within a path
+// segment and "**" matches any number of segments. Patterns without a "/"
+// are matched against the file name, others against the relative path.
+// With no Include patterns every artifact is included.
+type ArtifactDownloadOptions struct {
+	Include []string
+	Exclude []string
+	// Workers is the number of concurrent downloads, 4 by default.
+	Workers int
+	// Zip downloads all artifacts with a single request for archive.zip
+	// and extracts the selected ones.
+	Zip bool
+	// Progress is called as data comes in. Calls are serialized.
+	Progress func(ArtifactProgress)
+	// Verify selects how each file is verified, see VerifyMode.
+	Verify VerifyMode
+	// Manifest, when set, is the name of a SHA-256 manifest of the
+	// downloaded files written in dir, in sha256sum format.
+	Manifest string
+}
+
+// ArtifactProgress reports the progress of a download. In zip mode
+// RelativePath is "archive.zip" until extraction.
+type ArtifactProgress struct {
+	RelativePath string
+	Bytes        int64 // bytes of the file received so far
+	Done         bool  // the file is complete
+}
+
+// DownloadedArtifact is an artifact saved by DownloadArtifacts.
+type DownloadedArtifact struct {
+	RelativePath string
+	Path         string
+	Size         int64
+	Checksums
+}
+
+// WriteChecksumManifest writes the SHA-256 of the artifacts in sha256sum
+// format, sorted by relative path, so it can be checked with "sha256sum -c".
+func WriteChecksumManifest(w io.Writer, artifacts []DownloadedArtifact) error {
+	sorted := make([]DownloadedArtifact, len(artifacts))
+	copy(sorted, artifacts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].RelativePath < sorted[j].RelativePath })
+	for _, a := range sorted {
+		if _, err := fmt.Fprintf(w, "%s  %s\n", a.SHA256, a.RelativePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeManifest writes the checksum manifest to dir/name.
+func writeManifest(dir, name string, artifacts []DownloadedArtifact) error {
+	target, err := safeJoin(dir, name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if err := WriteChecksumManifest(f, artifacts); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// globRegexp compiles a glob pattern as described on ArtifactDownloadOptions.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				b.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// artifactFilter returns a function telling whether a relative path is selected.
+func artifactFilter(include, exclude []string) (func(string) bool, error) {
+	compile := func(patterns []string) ([]*regexp.Regexp, []bool, error) {
+		res := make([]*regexp.Regexp, len(patterns))
+		onName := make([]bool, len(patterns))
+		for i, p := range patterns {
+			re, err := globRegexp(p)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+			res[i], onName[i] = re, !strings.Contains(p, "/")
+		}
+		return res, onName, nil
+	}
+	inc, incOnName, err := compile(include)
+	if err != nil {
+		return nil, err
+	}
+	exc, excOnName, err := compile(exclude)
+	if err != nil {
+		return nil, err
+	}
+	matches := func(res []*regexp.Regexp, onName []bool, rel string) bool {
+		for i, re := range res {
+			if (onName[i] && re.MatchString(path.Base(rel))) || re.MatchString(rel) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(rel string) bool {
+		if len(inc) > 0 && !matches(inc, incOnName, rel) {
+			return false
+		}
+		return !matches(exc, excOnName, rel)
+	}, nil
+}
+
+// progressWriter counts the bytes written through it.
+type progressWriter struct {
+	w      io.Writer
+	rel    string
+	n      int64
+	report func(ArtifactProgress)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.n += int64(n)
+	pw.report(ArtifactProgress{RelativePath: pw.rel, Bytes: pw.n})
+	return n, err
+}
+
+// DownloadArtifacts saves the artifacts of the build below dir, keeping their
+// relative paths. Files are streamed to disk by a pool of workers; a file
+// interrupted in a previous call is resumed with a Range request when the
+// requester supports it. Checksums are computed while streaming and each
+// file is verified as set by opts.Verify. The artifact list is the one of
+// the last Poll.
+func (b *Build) DownloadArtifacts(ctx context.Context, dir string, opts *ArtifactDownloadOptions) ([]DownloadedArtifact, error) {
+	if opts == nil {
+		opts = &ArtifactDownloadOptions{}
+	}
+	keep, err := artifactFilter(opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	report := func(p ArtifactProgress) {
+		if opts.Progress != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			opts.Progress(p)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var results []DownloadedArtifact
+	if opts.Zip {
+		results, err = b.downloadArtifactsZip(ctx, dir, keep, opts.Verify, report)
+	} else {
+		results, err = b.downloadArtifactFiles(ctx, dir, keep, opts, report)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.Manifest != "" {
+		if err := writeManifest(dir, opts.Manifest, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// downloadArtifactFiles downloads the selected artifacts one request each.
+func (b *Build) downloadArtifactFiles(ctx context.Context, dir string, keep func(string) bool, opts *ArtifactDownloadOptions, report func(ArtifactProgress)) ([]DownloadedArtifact, error) {
+	selected := make([]string, 0, len(b.Raw.Artifacts))
+	for _, a := range b.Raw.Artifacts {
+		if keep(a.RelativePath) {
+			selected = append(selected, a.RelativePath)
+		}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = artifactWorkers
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]DownloadedArtifact, len(selected))
+	errs := make([]error, len(selected))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(selected)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i], errs[i] = b.downloadArtifact(ctx, dir, selected[i], opts.Verify, report)
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+feed:
+	for i := range selected {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("downloading artifact %s: %w", selected[i], err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// downloadArtifact streams a single artifact into dir/rel, resuming a
+// partial download left by an earlier attempt. A file failing verification
+// is removed.
+func (b *Build) downloadArtifact(ctx context.Context, dir string, rel string, mode VerifyMode, report func(ArtifactProgress)) (DownloadedArtifact, error) {
+	result := DownloadedArtifact{RelativePath: rel}
+	target, err := safeJoin(dir, rel)
+	if err != nil {
+		return result, err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return result, err
+	}
+	part := target + partialSuffix
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = f.Close() }()
+
+	endpoint := b.Base + "/artifact/" + rel
+	// the checksums also cover what an earlier attempt downloaded
+	sums := newChecksummer()
+	offset, err := io.Copy(sums, f)
+	if err != nil {
+		return result, err
+	}
+	pw := &progressWriter{w: io.MultiWriter(f, sums), rel: rel, n: offset, report: report}
+	rr, resumable := b.Jenkins.Requester.(RangeRequester)
+	if offset > 0 && resumable {
+		if err := resumeArtifact(ctx, rr, endpoint, f, sums, pw); err != nil {
+			return result, err
+		}
+	} else {
+		if err := f.Truncate(0); err != nil {
+			return result, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return result, err
+		}
+		sums.Reset()
+		pw.n = 0
+		resp, err := b.Jenkins.Requester.Get(ctx, endpoint, pw, nil)
+		if err != nil {
+			return result, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return result, fmt.Errorf("status %d", resp.StatusCode)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return result, err
+	}
+	result.Checksums = sums.Sum()
+	artifact := Artifact{Jenkins: b.Jenkins, Build: b, FileName: path.Base(rel), Path: endpoint}
+	if err := artifact.validateDownload(ctx, result.Checksums, mode); err != nil {
+		_ = os.Remove(part)
+		return result, err
+	}
+	if err := os.Rename(part, target); err != nil {
+		return result, err
+	}
+	result.Path, result.Size = target, pw.n
+	report(ArtifactProgress{RelativePath: rel, Bytes: pw.n, Done: true})
+	return result, nil
+}
+
+// resumeArtifact appends the rest of a partially downloaded artifact.
+func resumeArtifact(ctx context.Context, rr RangeRequester, endpoint string, f *os.File, sums *checksummer, pw *progressWriter) error {
+	resp, err := rr.GetRange(ctx, endpoint, pw.n)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file is already complete
+		return nil
+	case http.StatusOK:
+		// the server ignored the range and sends everything
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		sums.Reset()
+		pw.n = 0
+	default:
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	_, err = io.Copy(pw, resp.Body)
+	return err
+}
+
+// downloadArtifactsZip fetches archive.zip in one request and extracts the
+// selected artifacts from it, computing their checksums afterwards.
+func (b *Build) downloadArtifactsZip(ctx context.Context, dir string, keep func(string) bool, mode VerifyMode, report func(ArtifactProgress)) ([]DownloadedArtifact, error) {
+	tmp, err := os.CreateTemp("", "gojenkins-artifacts-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	pw := &progressWriter{w: tmp, rel: "archive.zip", report: report}
+	resp, err := b.Jenkins.Requester.Get(ctx, b.Base+"/artifact/*zip*/archive.zip", pw, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading archive.zip: status %d", resp.StatusCode)
+	}
+	report(ArtifactProgress{RelativePath: "archive.zip", Bytes: pw.n, Done: true})
+
+	zr, err := zip.NewReader(tmp, pw.n)
+	if err != nil {
+		return nil, err
+	}
+	// entries are stored below "archive/"
+	files, err := extractZip(zr, dir, 1, keep)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]DownloadedArtifact, 0, len(files))
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		sums, err := fileChecksums(file)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		artifact := Artifact{Jenkins: b.Jenkins, Build: b, FileName: path.Base(rel), Path: b.Base + "/artifact/" + rel}
+		if err := artifact.validateDownload(ctx, sums, mode); err != nil {
+			_ = os.Remove(file)
+			return nil, fmt.Errorf("verifying artifact %s: %w", rel, err)
+		}
+		results = append(results, DownloadedArtifact{RelativePath: rel, Path: file, Size: fi.Size(), Checksums: sums})
+	}
+	return results, nil
+}
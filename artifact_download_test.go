@@ -0,0 +1,179 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newArtifactBuild(files map[string]string) (*Build, *MockRequester) {
+	jenkins := newMockJenkins()
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		data, ok := files[strings.TrimPrefix(endpoint, "/job/app/1/artifact/")]
+		if !ok {
+			return &http.Response{StatusCode: 404}, nil
+		}
+		if str, ok := response.(*string); ok {
+			*str = data
+			return &http.Response{StatusCode: 200}, nil
+		}
+		_, err := io.WriteString(response.(io.Writer), data)
+		return &http.Response{StatusCode: 200}, err
+	}
+	raw := &BuildResponse{Number: 1}
+	for name := range files {
+		raw.Artifacts = append(raw.Artifacts, struct {
+			DisplayPath  string `json:"displayPath"`
+			FileName     string `json:"fileName"`
+			RelativePath string `json:"relativePath"`
+		}{DisplayPath: name, FileName: filepath.Base(name), RelativePath: name})
+	}
+	sort.Slice(raw.Artifacts, func(i, j int) bool { return raw.Artifacts[i].RelativePath < raw.Artifacts[j].RelativePath })
+	return &Build{Jenkins: jenkins, Raw: raw, Base: "/job/app/1"}, mock
+}
+
+func TestArtifactFilter(t *testing.T) {
+	keep, err := artifactFilter([]string{"*.jar", "docs/**"}, []string{"**/*-sources.jar"})
+	require.NoError(t, err)
+	assert.True(t, keep("target/app.jar"))
+	assert.True(t, keep("docs/api/index.html"))
+	assert.False(t, keep("target/app-sources.jar"))
+	assert.False(t, keep("target/app.war"))
+
+	keep, err = artifactFilter(nil, nil)
+	require.NoError(t, err)
+	assert.True(t, keep("anything/at/all"))
+}
+
+func TestBuild_DownloadArtifacts(t *testing.T) {
+	build, _ := newArtifactBuild(map[string]string{
+		"target/app.jar":         "jar",
+		"target/app-sources.jar": "sources",
+		"reports/index.html":     "<html>",
+	})
+	dir := t.TempDir()
+	done := make([]string, 0)
+	files, err := build.DownloadArtifacts(context.Background(), dir, &ArtifactDownloadOptions{
+		Exclude: []string{"*-sources.jar"},
+		Progress: func(p ArtifactProgress) {
+			if p.Done {
+				done = append(done, p.RelativePath)
+			}
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(files))
+	assert.Equal(t, "reports/index.html", files[0].RelativePath)
+	assert.Equal(t, filepath.Join(dir, "reports", "index.html"), files[0].Path)
+	assert.Equal(t, int64(6), files[0].Size)
+	data, err := os.ReadFile(filepath.Join(dir, "target", "app.jar"))
+	require.NoError(t, err)
+	assert.Equal(t, "jar", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "target", "app-sources.jar"))
+	assert.ElementsMatch(t, []string{"reports/index.html", "target/app.jar"}, done)
+}
+
+func TestBuild_DownloadArtifacts_Resume(t *testing.T) {
+	build, mock := newArtifactBuild(map[string]string{"big.bin": "0123456789"})
+	var offset int64 = -1
+	mock.GetRangeFunc = func(ctx context.Context, endpoint string, from int64) (*http.Response, error) {
+		assert.Equal(t, "/job/app/1/artifact/big.bin", endpoint)
+		offset = from
+		return &http.Response{StatusCode: http.StatusPartialContent, Body: io.NopCloser(strings.NewReader("0123456789"[from:]))}, nil
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.bin"+partialSuffix), []byte("0123"), 0644))
+
+	files, err := build.DownloadArtifacts(context.Background(), dir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), offset)
+	assert.Equal(t, int64(10), files[0].Size)
+	assert.Equal(t, "781e5e245d69b566979b86e28d23f2c7", files[0].MD5)
+	data, err := os.ReadFile(filepath.Join(dir, "big.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "big.bin"+partialSuffix))
+}
+
+func TestBuild_DownloadArtifacts_Zip(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, data := range map[string]string{"archive/target/app.jar": "jar", "archive/notes.txt": "notes"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	build, _ := newArtifactBuild(map[string]string{"*zip*/archive.zip": archive.String()})
+
+	dir := t.TempDir()
+	files, err := build.DownloadArtifacts(context.Background(), dir, &ArtifactDownloadOptions{Zip: true, Include: []string{"**/*.jar"}})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+	assert.Equal(t, "target/app.jar", files[0].RelativePath)
+	assert.Equal(t, int64(3), files[0].Size)
+	assert.NoFileExists(t, filepath.Join(dir, "notes.txt"))
+}
+
+func TestBuild_DownloadArtifacts_UnsafePath(t *testing.T) {
+	build, _ := newArtifactBuild(map[string]string{"../escape.txt": "x"})
+	_, err := build.DownloadArtifacts(context.Background(), t.TempDir(), nil)
+	assert.Error(t, err)
+}
+
+func TestBuild_DownloadArtifacts_Manifest(t *testing.T) {
+	build, _ := newArtifactBuild(map[string]string{
+		"b/two.txt":            "two",
+		"one.txt":              "one",
+		"one.txt.sha256":       "7692c3ad3540bb803c020b3aee66cd8887123234ea0c6e7143c0add73ff431ed  one.txt",
+		"b/two.txt.sha256":     "3fc4ccfe745870e2c0d99f71f30ff0656c8dedd41cc1d7d3d376b0dbe685e2f3",
+		"b/three.txt":          "three",
+		"b/three.txt.sha256":   "0000",
+		"reports/ignored.html": "",
+	})
+	dir := t.TempDir()
+	files, err := build.DownloadArtifacts(context.Background(), dir, &ArtifactDownloadOptions{
+		Include:  []string{"*.txt"},
+		Exclude:  []string{"three.txt"},
+		Verify:   VerifySidecar,
+		Manifest: "SHA256SUMS",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(files))
+	manifest, err := os.ReadFile(filepath.Join(dir, "SHA256SUMS"))
+	require.NoError(t, err)
+	assert.Equal(t, "3fc4ccfe745870e2c0d99f71f30ff0656c8dedd41cc1d7d3d376b0dbe685e2f3  b/two.txt\n"+
+		"7692c3ad3540bb803c020b3aee66cd8887123234ea0c6e7143c0add73ff431ed  one.txt\n", string(manifest))
+
+	_, err = build.DownloadArtifacts(context.Background(), dir, &ArtifactDownloadOptions{Include: []string{"three.txt"}, Verify: VerifySidecar})
+	var mismatch *ErrChecksumMismatch
+	assert.ErrorAs(t, err, &mismatch)
+	assert.NoFileExists(t, filepath.Join(dir, "b", "three.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "b", "three.txt"+partialSuffix))
+}
@@ -2,7 +2,10 @@ package gojenkins
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -89,15 +92,92 @@ func TestArtifactGetDataError(t *testing.T) {
 	assert.Nil(t, data)
 }
 
-// TestArtifactGetMD5Local tests MD5 calculation for local files
-func TestArtifactGetMD5Local(t *testing.T) {
+// TestFileChecksums tests checksum calculation for local files
+func TestFileChecksums(t *testing.T) {
+	// Test with non-existent file - should fail instead of returning an empty hash
+	_, err := fileChecksums("/nonexistent/path/file.txt")
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "test.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+	sums, err := fileChecksums(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", sums.MD5)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", sums.SHA256)
+}
+
+// TestArtifactDownloadVerify tests the verification modes of Download
+func TestArtifactDownloadVerify(t *testing.T) {
+	sidecar := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  app.jar\n"
+	mock := &MockRequester{
+		GetFunc: func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+			switch r := response.(type) {
+			case io.Writer:
+				_, _ = io.WriteString(r, "hello")
+			case *string:
+				*r = sidecar
+			}
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		},
+		GetJSONFunc: func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+			assert.Equal(t, "/fingerprint/5d41402abc4b2a76b9719d911017c592", endpoint)
+			response.(*FingerPrintResponse).Hash = "5d41402abc4b2a76b9719d911017c592"
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		},
+	}
 	artifact := Artifact{
-		FileName: "test.txt",
+		Jenkins:  &Jenkins{Server: "http://jenkins.local", Requester: mock},
+		FileName: "app.jar",
+		Path:     "/job/TestJob/1/artifact/app.jar",
 	}
+	path := filepath.Join(t.TempDir(), "app.jar")
+	ctx := context.Background()
 
-	// Test with non-existent file - should return empty string
-	hash := artifact.getMD5local("/nonexistent/path/file.txt")
-	assert.Empty(t, hash)
+	for _, mode := range []VerifyMode{VerifyOff, VerifyFingerprint, VerifySidecar} {
+		sums, err := artifact.Download(ctx, path, mode)
+		assert.NoError(t, err)
+		assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", sums.MD5)
+	}
+
+	sidecar = "0000  app.jar"
+	_, err := artifact.Download(ctx, path, VerifySidecar)
+	var mismatch *ErrChecksumMismatch
+	assert.ErrorAs(t, err, &mismatch)
+	// the copy downloaded before is kept
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
+
+// TestArtifactDownloadKeepsFileOnError tests that a failed download leaves an
+// existing file alone
+func TestArtifactDownloadKeepsFileOnError(t *testing.T) {
+	mock := &MockRequester{
+		GetFunc: func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+			_, _ = io.WriteString(response.(io.Writer), "<html>Not Found</html>")
+			return &http.Response{StatusCode: http.StatusNotFound}, nil
+		},
+	}
+	artifact := Artifact{
+		Jenkins:  &Jenkins{Server: "http://jenkins.local", Requester: mock},
+		FileName: "app.jar",
+		Path:     "/job/TestJob/1/artifact/app.jar",
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.jar")
+	assert.NoError(t, os.WriteFile(path, []byte("previous build"), 0644))
+
+	_, err := artifact.Download(context.Background(), path, VerifyOff)
+	assert.EqualError(t, err, "could not get file contents: status 404")
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "previous build", string(data))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
 }
 
 // TestArtifactPathConstruction verifies artifact path is correctly used
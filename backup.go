@@ -0,0 +1,571 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	folderClass  = "com.cloudbees.hudson.plugins.folder.Folder"
+	allViewClass = "hudson.model.AllView"
+	manifestFile = "manifest.json"
+)
+
+// Item kinds recorded in an export manifest.
+const (
+	ItemKindFolder = "folder"
+	ItemKindJob    = "job"
+	ItemKindView   = "view"
+)
+
+// ConflictStrategy decides what ImportItems does when an item already exists.
+type ConflictStrategy int
+
+const (
+	// ConflictSkip leaves the existing item untouched. Children of a skipped
+	// folder are still imported into the existing folder.
+	ConflictSkip ConflictStrategy = iota
+	// ConflictOverwrite replaces the configuration of the existing item.
+	ConflictOverwrite
+	// ConflictRename imports the item under a new, unused name.
+	ConflictRename
+)
+
+// Actions reported by ImportItems for each item.
+const (
+	ImportCreated     = "created"
+	ImportOverwritten = "overwritten"
+	ImportSkipped     = "skipped"
+	ImportRenamed     = "renamed"
+)
+
+// ExportManifest describes the contents of an archive written by ExportItems.
+type ExportManifest struct {
+	Server  string         `json:"server"`
+	Version string         `json:"version"`
+	Root    string         `json:"root"`
+	Created time.Time      `json:"created"`
+	Items   []ExportedItem `json:"items"`
+}
+
+// ExportedItem is a single folder, job or view stored in an export archive.
+// Parent is the full name of the containing folder, empty for the top level.
+type ExportedItem struct {
+	Kind     string `json:"kind"`
+	Class    string `json:"_class"`
+	FullName string `json:"fullName"`
+	Parent   string `json:"parent"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	SHA256   string `json:"sha256"`
+}
+
+// ImportOptions controls how ImportItems recreates an archive.
+type ImportOptions struct {
+	// Destination is the folder the export root is recreated in. Empty
+	// restores items at their original location, "/" means the top level.
+	Destination string
+	Conflict    ConflictStrategy
+	// RenameSuffix is appended to conflicting names with ConflictRename.
+	// Defaults to "-imported".
+	RenameSuffix string
+}
+
+// ImportResult reports what happened to a single archived item.
+type ImportResult struct {
+	Item     ExportedItem
+	FullName string
+	Action   string
+}
+
+type itemListing struct {
+	Class string     `json:"_class"`
+	Jobs  []InnerJob `json:"jobs"`
+	Views []struct {
+		Class string `json:"_class"`
+		Name  string `json:"name"`
+	} `json:"views"`
+}
+
+// itemBase returns the URL path of an item from its full name, e.g. "a/b" -> "/job/a/job/b".
+func itemBase(fullName string) string {
+	if fullName == "" {
+		return ""
+	}
+	return "/job/" + strings.Join(strings.Split(fullName, "/"), "/job/")
+}
+
+// viewBase returns the URL path of a view inside the folder with the given full name.
+func viewBase(parent string, name string) string {
+	return itemBase(parent) + "/view/" + name
+}
+
+// archiveDir mirrors the JENKINS_HOME layout, e.g. "a/b" -> "jobs/a/jobs/b/".
+func archiveDir(fullName string) string {
+	if fullName == "" {
+		return ""
+	}
+	return "jobs/" + strings.Join(strings.Split(fullName, "/"), "/jobs/") + "/"
+}
+
+func splitFullName(fullName string) (parent string, name string) {
+	if i := strings.LastIndex(fullName, "/"); i >= 0 {
+		return fullName[:i], fullName[i+1:]
+	}
+	return "", fullName
+}
+
+func joinFullName(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+func parentIDs(fullName string) []string {
+	if fullName == "" {
+		return nil
+	}
+	return strings.Split(fullName, "/")
+}
+
+func (j *Jenkins) listItems(ctx context.Context, fullName string) (*itemListing, error) {
+	endpoint := itemBase(fullName)
+	if endpoint == "" {
+		endpoint = "/"
+	}
+	listing := new(itemListing)
+	qr := map[string]string{"tree": "_class,jobs[name,_class],views[name,_class]"}
+	if _, err := j.Requester.GetJSON(ctx, endpoint, listing, qr); err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
+
+// itemExists reports whether anything answers at the given item or view base.
+func (j *Jenkins) itemExists(ctx context.Context, base string) (bool, error) {
+	var probe struct{}
+	resp, err := j.Requester.GetJSON(ctx, base, &probe, map[string]string{"tree": "_class"})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+type exporter struct {
+	jenkins  *Jenkins
+	tw       *tar.Writer
+	manifest *ExportManifest
+}
+
+func (e *exporter) add(item ExportedItem, config string) error {
+	sum := sha256.Sum256([]byte(config))
+	item.SHA256 = hex.EncodeToString(sum[:])
+	if err := e.writeFile(item.Path, []byte(config)); err != nil {
+		return err
+	}
+	e.manifest.Items = append(e.manifest.Items, item)
+	return nil
+}
+
+func (e *exporter) writeFile(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: e.manifest.Created,
+	}
+	if err := e.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := e.tw.Write(data)
+	return err
+}
+
+// config reads the config.xml of the item or view at base. Error pages
+// must not end up in the archive, so anything but 200 is an error.
+func (e *exporter) config(ctx context.Context, base string) (string, error) {
+	var config string
+	resp, err := e.jenkins.Requester.GetXML(ctx, base+"/config.xml", &config, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return config, nil
+}
+
+func (e *exporter) addItem(ctx context.Context, fullName string, class string) error {
+	kind := ItemKindJob
+	if class == folderClass {
+		kind = ItemKindFolder
+	}
+	config, err := e.config(ctx, itemBase(fullName))
+	if err != nil {
+		return fmt.Errorf("reading config of %s: %w", fullName, err)
+	}
+	parent, name := splitFullName(fullName)
+	item := ExportedItem{
+		Kind:     kind,
+		Class:    class,
+		FullName: fullName,
+		Parent:   parent,
+		Name:     name,
+		Path:     archiveDir(fullName) + "config.xml",
+	}
+	if err := e.add(item, config); err != nil {
+		return err
+	}
+	if kind == ItemKindFolder {
+		return e.walk(ctx, fullName)
+	}
+	return nil
+}
+
+func (e *exporter) walk(ctx context.Context, folder string) error {
+	listing, err := e.jenkins.listItems(ctx, folder)
+	if err != nil {
+		return err
+	}
+	for _, job := range listing.Jobs {
+		if err := e.addItem(ctx, joinFullName(folder, job.Name), job.Class); err != nil {
+			return err
+		}
+	}
+	for _, v := range listing.Views {
+		if v.Class == allViewClass {
+			continue
+		}
+		config, err := e.config(ctx, viewBase(folder, v.Name))
+		if err != nil {
+			return fmt.Errorf("reading config of view %s: %w", v.Name, err)
+		}
+		item := ExportedItem{
+			Kind:     ItemKindView,
+			Class:    v.Class,
+			FullName: joinFullName(folder, v.Name),
+			Parent:   folder,
+			Name:     v.Name,
+			Path:     archiveDir(folder) + "views/" + v.Name + "/config.xml",
+		}
+		if err := e.add(item, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportItems writes a tar.gz archive of every folder, job and view config.xml
+// under root to w. An empty root exports the whole controller.
+// Files are laid out like JENKINS_HOME (jobs/<name>/jobs/<name>/config.xml) and
+// a manifest.json lists every item, parents first, with its SHA-256 checksum.
+// Example: jenkins.ExportItems(ctx, "team/backend", file)
+func (j *Jenkins) ExportItems(ctx context.Context, root string, w io.Writer) (*ExportManifest, error) {
+	root = strings.Trim(root, "/")
+	manifest := &ExportManifest{
+		Server:  j.Server,
+		Version: j.Version,
+		Root:    root,
+		Created: time.Now().UTC().Truncate(time.Second),
+		Items:   make([]ExportedItem, 0),
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	e := &exporter{jenkins: j, tw: tw, manifest: manifest}
+
+	var err error
+	if root == "" {
+		err = e.walk(ctx, "")
+	} else {
+		var listing *itemListing
+		listing, err = j.listItems(ctx, root)
+		if err == nil {
+			err = e.addItem(ctx, root, listing.Class)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := e.writeFile(manifestFile, data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ReadExportArchive reads an archive written by ExportItems and returns its
+// manifest together with the config.xml contents keyed by archive path.
+// Every config is checked against the checksum recorded in the manifest.
+func ReadExportArchive(r io.Reader) (*ExportManifest, map[string]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = gz.Close() }()
+
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[path.Clean(hdr.Name)] = string(data)
+	}
+
+	raw, ok := files[manifestFile]
+	if !ok {
+		return nil, nil, errors.New("archive has no " + manifestFile)
+	}
+	manifest := new(ExportManifest)
+	if err := json.Unmarshal([]byte(raw), manifest); err != nil {
+		return nil, nil, err
+	}
+	configs := make(map[string]string, len(manifest.Items))
+	for _, item := range manifest.Items {
+		config, ok := files[path.Clean(item.Path)]
+		if !ok {
+			return nil, nil, fmt.Errorf("archive is missing %s", item.Path)
+		}
+		sum := sha256.Sum256([]byte(config))
+		if hex.EncodeToString(sum[:]) != item.SHA256 {
+			return nil, nil, fmt.Errorf("checksum mismatch for %s", item.Path)
+		}
+		configs[item.Path] = config
+	}
+	return manifest, configs, nil
+}
+
+type importer struct {
+	jenkins *Jenkins
+	opts    ImportOptions
+	srcBase string
+	dest    string
+	// placed maps the relocated full name of a folder to where it really ended up
+	placed map[string]string
+}
+
+// relocate moves an archived full name below the destination folder.
+func (imp *importer) relocate(fullName string) string {
+	rel := fullName
+	if imp.srcBase != "" {
+		if fullName == imp.srcBase {
+			return imp.dest
+		}
+		rel = strings.TrimPrefix(fullName, imp.srcBase+"/")
+	}
+	if rel == "" {
+		return imp.dest
+	}
+	return joinFullName(imp.dest, rel)
+}
+
+func (imp *importer) parentOf(item ExportedItem) string {
+	parent := imp.relocate(item.Parent)
+	if placed, ok := imp.placed[parent]; ok {
+		return placed
+	}
+	return parent
+}
+
+func (imp *importer) base(kind string, parent string, name string) string {
+	if kind == ItemKindView {
+		return viewBase(parent, name)
+	}
+	return itemBase(joinFullName(parent, name))
+}
+
+func (imp *importer) freeName(ctx context.Context, kind string, parent string, name string) (string, error) {
+	candidate := name + imp.opts.RenameSuffix
+	for i := 2; ; i++ {
+		exists, err := imp.jenkins.itemExists(ctx, imp.base(kind, parent, candidate))
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%s-%d", name, imp.opts.RenameSuffix, i)
+	}
+}
+
+func (imp *importer) create(ctx context.Context, item ExportedItem, parent string, name string, config string) error {
+	j := imp.jenkins
+	switch item.Kind {
+	case ItemKindFolder:
+		folder, err := j.CreateFolder(ctx, name, parentIDs(parent)...)
+		if err != nil {
+			return err
+		}
+		return folder.UpdateConfig(ctx, config)
+	case ItemKindView:
+		if parent == "" {
+			view, err := j.CreateView(ctx, name, item.Class)
+			if err != nil {
+				return err
+			}
+			return view.UpdateConfig(ctx, config)
+		}
+		resp, err := j.Requester.PostXML(ctx, itemBase(parent)+"/createView", config, nil, map[string]string{"name": name})
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("creating view %s: status %d", name, resp.StatusCode)
+		}
+		return nil
+	default:
+		_, err := j.CreateJobInFolder(ctx, config, name, parentIDs(parent)...)
+		return err
+	}
+}
+
+func (imp *importer) overwrite(ctx context.Context, item ExportedItem, parent string, name string, config string) error {
+	j := imp.jenkins
+	base := imp.base(item.Kind, parent, name)
+	switch item.Kind {
+	case ItemKindFolder:
+		folder := &Folder{Jenkins: j, Raw: new(FolderResponse), Base: base}
+		return folder.UpdateConfig(ctx, config)
+	case ItemKindView:
+		view := &View{Jenkins: j, Raw: new(ViewResponse), Base: base}
+		return view.UpdateConfig(ctx, config)
+	default:
+		job := &Job{Jenkins: j, Raw: new(JobResponse), Base: base}
+		return job.UpdateConfig(ctx, config)
+	}
+}
+
+func (imp *importer) restore(ctx context.Context, item ExportedItem, config string) (ImportResult, error) {
+	parent := imp.parentOf(item)
+	name := item.Name
+	result := ImportResult{Item: item, FullName: joinFullName(parent, name)}
+
+	exists, err := imp.jenkins.itemExists(ctx, imp.base(item.Kind, parent, name))
+	if err != nil {
+		return result, err
+	}
+	switch {
+	case !exists:
+		result.Action = ImportCreated
+		err = imp.create(ctx, item, parent, name, config)
+	case imp.opts.Conflict == ConflictOverwrite:
+		result.Action = ImportOverwritten
+		err = imp.overwrite(ctx, item, parent, name, config)
+	case imp.opts.Conflict == ConflictRename:
+		result.Action = ImportRenamed
+		name, err = imp.freeName(ctx, item.Kind, parent, name)
+		if err == nil {
+			result.FullName = joinFullName(parent, name)
+			err = imp.create(ctx, item, parent, name, config)
+		}
+	default:
+		result.Action = ImportSkipped
+	}
+	if err != nil {
+		return result, fmt.Errorf("importing %s %s: %w", item.Kind, item.FullName, err)
+	}
+	if item.Kind == ItemKindFolder {
+		imp.placed[imp.relocate(item.FullName)] = result.FullName
+	}
+	return result, nil
+}
+
+// ImportItems recreates the folders, jobs and views of an archive written by
+// ExportItems, on the same or another controller.
+// Folders and jobs are created parents first and views last, so that views can
+// reference the jobs they list. The returned results cover every item processed
+// before an error, if any.
+func (j *Jenkins) ImportItems(ctx context.Context, r io.Reader, opts *ImportOptions) ([]ImportResult, error) {
+	manifest, configs, err := ReadExportArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	return j.importConfigs(ctx, manifest, configs, opts)
+}
+
+// importConfigs restores already decoded archive contents, see ImportItems.
+func (j *Jenkins) importConfigs(ctx context.Context, manifest *ExportManifest, configs map[string]string, opts *ImportOptions) ([]ImportResult, error) {
+	imp := &importer{jenkins: j, placed: make(map[string]string)}
+	if opts != nil {
+		imp.opts = *opts
+	}
+	if imp.opts.RenameSuffix == "" {
+		imp.opts.RenameSuffix = "-imported"
+	}
+	imp.srcBase, _ = splitFullName(manifest.Root)
+	imp.dest = imp.srcBase
+	if imp.opts.Destination != "" {
+		imp.dest = strings.Trim(imp.opts.Destination, "/")
+	}
+
+	items := make([]ExportedItem, len(manifest.Items))
+	copy(items, manifest.Items)
+	sort.SliceStable(items, func(a, b int) bool {
+		va, vb := items[a].Kind == ItemKindView, items[b].Kind == ItemKindView
+		if va != vb {
+			return vb
+		}
+		return strings.Count(items[a].FullName, "/") < strings.Count(items[b].FullName, "/")
+	})
+
+	results := make([]ImportResult, 0, len(items))
+	for _, item := range items {
+		result, err := imp.restore(ctx, item, configs[item.Path])
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newExportMock serves a controller with one folder "team" holding a job and a
+// list view, plus a top-level job.
+func newExportMock() *Jenkins {
+	jenkins := newMockJenkins()
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		listing, ok := response.(*itemListing)
+		if !ok {
+			return &http.Response{StatusCode: 200}, nil
+		}
+		switch endpoint {
+		case "/":
+			listing.Jobs = []InnerJob{
+				{Name: "team", Class: folderClass},
+				{Name: "standalone", Class: "hudson.model.FreeStyleProject"},
+			}
+			listing.Views = append(listing.Views, struct {
+				Class string `json:"_class"`
+				Name  string `json:"name"`
+			}{Class: allViewClass, Name: "all"})
+		case "/job/team":
+			listing.Class = folderClass
+			listing.Jobs = []InnerJob{{Name: "api", Class: "org.jenkinsci.plugins.workflow.job.WorkflowJob"}}
+			listing.Views = append(listing.Views, struct {
+				Class string `json:"_class"`
+				Name  string `json:"name"`
+			}{Class: "hudson.model.ListView", Name: "mine"})
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.GetXMLFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		*response.(*string) = "<config>" + endpoint + "</config>"
+		return &http.Response{StatusCode: 200}, nil
+	}
+	return jenkins
+}
+
+func TestExportItems_WholeController(t *testing.T) {
+	jenkins := newExportMock()
+	var buf bytes.Buffer
+
+	manifest, err := jenkins.ExportItems(context.Background(), "", &buf)
+	require.NoError(t, err)
+
+	paths := make([]string, len(manifest.Items))
+	for i, item := range manifest.Items {
+		paths[i] = item.Path
+	}
+	assert.Equal(t, []string{
+		"jobs/team/config.xml",
+		"jobs/team/jobs/api/config.xml",
+		"jobs/team/views/mine/config.xml",
+		"jobs/standalone/config.xml",
+	}, paths)
+	assert.Equal(t, ItemKindFolder, manifest.Items[0].Kind)
+	assert.Equal(t, "team/api", manifest.Items[1].FullName)
+	assert.Equal(t, ItemKindView, manifest.Items[2].Kind)
+
+	read, configs, err := ReadExportArchive(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Items, read.Items)
+	assert.Equal(t, "<config>/job/team/job/api/config.xml</config>", configs["jobs/team/jobs/api/config.xml"])
+}
+
+func TestExportItems_Subtree(t *testing.T) {
+	jenkins := newExportMock()
+	var buf bytes.Buffer
+
+	manifest, err := jenkins.ExportItems(context.Background(), "/team/", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "team", manifest.Root)
+	assert.Equal(t, 3, len(manifest.Items))
+}
+
+func TestExportItems_ConfigErrorPage(t *testing.T) {
+	jenkins := newExportMock()
+	jenkins.Requester.(*MockRequester).GetXMLFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		*response.(*string) = "<html>Access Denied</html>"
+		return &http.Response{StatusCode: 403}, nil
+	}
+	var buf bytes.Buffer
+
+	_, err := jenkins.ExportItems(context.Background(), "", &buf)
+	assert.EqualError(t, err, "reading config of team: status 403")
+}
+
+func TestReadExportArchive_ChecksumMismatch(t *testing.T) {
+	manifest := &ExportManifest{Items: []ExportedItem{
+		{Kind: ItemKindJob, FullName: "job", Name: "job", Path: "jobs/job/config.xml", SHA256: "deadbeef"},
+	}}
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{manifestFile: string(data), "jobs/job/config.xml": "<project/>"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+		_, err = tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	_, _, err = ReadExportArchive(&buf)
+	assert.Error(t, err)
+}
+
+func TestImportItems_CreatesInOrder(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := newExportMock().ExportItems(context.Background(), "team", &buf)
+	require.NoError(t, err)
+
+	jenkins := newMockJenkins()
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		if query["tree"] == "_class" {
+			return &http.Response{StatusCode: 404}, nil
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	var calls []string
+	mock.PostFunc = func(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+		calls = append(calls, "POST "+endpoint+" "+query["name"])
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.PostXMLFunc = func(ctx context.Context, endpoint string, xml string, response interface{}, query map[string]string) (*http.Response, error) {
+		calls = append(calls, "XML "+endpoint+" "+query["name"])
+		return &http.Response{StatusCode: 200}, nil
+	}
+
+	results, err := jenkins.ImportItems(context.Background(), &buf, &ImportOptions{Destination: "restored"})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(results))
+	assert.Equal(t, "restored/team", results[0].FullName)
+	assert.Equal(t, "restored/team/api", results[1].FullName)
+	assert.Equal(t, "restored/team/mine", results[2].FullName)
+	for _, r := range results {
+		assert.Equal(t, ImportCreated, r.Action)
+	}
+	assert.Equal(t, []string{
+		"POST /job/restored/createItem team",
+		"XML /job/restored/job/team/config.xml ",
+		"XML /job/restored/job/team/createItem api",
+		"XML /job/restored/job/team/createView mine",
+	}, calls)
+}
+
+func TestImportItems_ConflictStrategies(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := newExportMock().ExportItems(context.Background(), "team", &buf)
+	require.NoError(t, err)
+	archive := buf.Bytes()
+
+	newTarget := func(existing map[string]bool, calls *[]string) *Jenkins {
+		jenkins := newMockJenkins()
+		mock := jenkins.Requester.(*MockRequester)
+		mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+			if query["tree"] == "_class" && !existing[endpoint] {
+				return &http.Response{StatusCode: 404}, nil
+			}
+			return &http.Response{StatusCode: 200}, nil
+		}
+		mock.PostFunc = func(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+			*calls = append(*calls, endpoint+" "+query["name"])
+			return &http.Response{StatusCode: 200}, nil
+		}
+		mock.PostXMLFunc = func(ctx context.Context, endpoint string, xml string, response interface{}, query map[string]string) (*http.Response, error) {
+			*calls = append(*calls, endpoint+" "+query["name"])
+			return &http.Response{StatusCode: 200}, nil
+		}
+		return jenkins
+	}
+	existing := map[string]bool{"/job/team": true, "/job/team/job/api": true}
+
+	t.Run("skip", func(t *testing.T) {
+		var calls []string
+		results, err := newTarget(existing, &calls).ImportItems(context.Background(), bytes.NewReader(archive), nil)
+		require.NoError(t, err)
+		assert.Equal(t, ImportSkipped, results[0].Action)
+		assert.Equal(t, ImportSkipped, results[1].Action)
+		assert.Equal(t, ImportCreated, results[2].Action)
+		assert.Equal(t, []string{"/job/team/createView mine"}, calls)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		var calls []string
+		opts := &ImportOptions{Conflict: ConflictOverwrite}
+		results, err := newTarget(existing, &calls).ImportItems(context.Background(), bytes.NewReader(archive), opts)
+		require.NoError(t, err)
+		assert.Equal(t, ImportOverwritten, results[1].Action)
+		assert.Contains(t, calls, "/job/team/job/api/config.xml ")
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		var calls []string
+		opts := &ImportOptions{Conflict: ConflictRename}
+		results, err := newTarget(existing, &calls).ImportItems(context.Background(), bytes.NewReader(archive), opts)
+		require.NoError(t, err)
+		assert.Equal(t, ImportRenamed, results[0].Action)
+		assert.Equal(t, "team-imported", results[0].FullName)
+		// children follow the renamed folder
+		assert.Equal(t, "team-imported/api", results[1].FullName)
+		assert.Equal(t, ImportCreated, results[1].Action)
+	})
+}
@@ -0,0 +1,217 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package blueocean is a client of the Blue Ocean REST API of Jenkins
+// (/blue/rest/organizations/...), which describes pipelines, branches, runs
+// and their nodes and steps in more detail than wfapi.
+//
+// Requests go through the requester of a gojenkins client, which handles
+// authentication and CSRF crumbs:
+//
+//	jenkins := gojenkins.CreateJenkins(nil, "http://localhost:8080/", "admin", "token")
+//	bo := blueocean.New(jenkins.Requester)
+//	pipeline, err := bo.Pipeline(ctx, "folder/app")
+package blueocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// DefaultOrganization is the organization of a Jenkins without the
+// organization folders plugin.
+const DefaultOrganization = "jenkins"
+
+// number of items fetched per request by default when listing
+const defaultPageSize = 100
+
+// ErrNotFound is returned when Blue Ocean has no such resource.
+var ErrNotFound = errors.New("not found")
+
+// Client reads the Blue Ocean REST API of one organization.
+type Client struct {
+	Requester    gojenkins.JenkinsRequester
+	Organization string
+	// PageSize is the number of items fetched per request when listing.
+	PageSize int
+}
+
+// New returns a client of the default organization using requester,
+// usually the Requester of a gojenkins.Jenkins.
+func New(requester gojenkins.JenkinsRequester) *Client {
+	return &Client{Requester: requester, Organization: DefaultOrganization, PageSize: defaultPageSize}
+}
+
+func (c *Client) base() string {
+	return "/blue/rest/organizations/" + url.PathEscape(c.Organization)
+}
+
+// get decodes the JSON resource at endpoint into v.
+func (c *Client) get(ctx context.Context, endpoint string, v interface{}, query map[string]string) error {
+	resp, err := c.Requester.Get(ctx, endpoint, v, query)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", endpoint, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("reading %s failed: status %d", endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// list fetches the collection at endpoint page by page, calling add with
+// every item, until a page comes back short or max items were read. A max
+// of 0 reads the whole collection.
+func (c *Client) list(ctx context.Context, endpoint string, max int, add func(raw json.RawMessage) error) error {
+	size := c.PageSize
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	read := 0
+	for {
+		limit := size
+		if max > 0 {
+			limit = min(size, max-read)
+		}
+		var page []json.RawMessage
+		query := map[string]string{"start": strconv.Itoa(read), "limit": strconv.Itoa(limit)}
+		if err := c.get(ctx, endpoint, &page, query); err != nil {
+			return err
+		}
+		for _, raw := range page {
+			if err := add(raw); err != nil {
+				return err
+			}
+		}
+		read += len(page)
+		if len(page) < limit || (max > 0 && read >= max) {
+			return nil
+		}
+	}
+}
+
+// post sends payload as JSON to endpoint and decodes the answer into v,
+// unless v is nil.
+func (c *Client) post(ctx context.Context, endpoint string, payload interface{}, v interface{}) error {
+	poster, ok := c.Requester.(gojenkins.JSONBodyRequester)
+	if !ok {
+		return errors.New("requester cannot post JSON bodies")
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		v = new(json.RawMessage)
+	}
+	resp, err := poster.PostJSONBody(ctx, endpoint, bytes.NewReader(body), v)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", endpoint, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("posting to %s failed: status %d", endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// Log is a chunk of a log read from an offset.
+type Log struct {
+	Text string
+	// Offset is where to continue reading from.
+	Offset int64
+	// HasMore is true while the log may still grow.
+	HasMore bool
+}
+
+// log reads the log at endpoint from offset start.
+func (c *Client) log(ctx context.Context, endpoint string, start int64) (*Log, error) {
+	var text string
+	resp, err := c.Requester.Get(ctx, endpoint, &text, map[string]string{"start": strconv.FormatInt(start, 10)})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", endpoint, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading %s failed: status %d", endpoint, resp.StatusCode)
+	}
+	log := &Log{Text: text, Offset: start + int64(len(text)), HasMore: resp.Header.Get("X-More-Data") == "true"}
+	if size := resp.Header.Get("X-Text-Size"); size != "" {
+		if log.Offset, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return log, nil
+}
+
+// pipelinePath returns the path of a pipeline from its full name, where
+// folders are separated by slashes.
+func pipelinePath(fullName string) string {
+	parts := strings.Split(strings.Trim(fullName, "/"), "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return "/pipelines/" + strings.Join(parts, "/pipelines/")
+}
+
+// Blue Ocean time format, e.g. 2024-03-01T10:15:30.123+0000
+const timeLayout = "2006-01-02T15:04:05.000-0700"
+
+// Time is a time as formatted by Blue Ocean. It is zero for null values,
+// e.g. the end time of a running run.
+type Time struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == nil || *s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	parsed, err := time.Parse(timeLayout, *s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Format(timeLayout))
+}
@@ -0,0 +1,160 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const org = "/blue/rest/organizations/jenkins"
+
+// fakeRequester serves JSON resources and logs by endpoint. Collections are
+// paged by the start and limit parameters like Blue Ocean does.
+type fakeRequester struct {
+	t         *testing.T
+	resources map[string]string
+	logs      map[string]string
+	// gets counts the requests per endpoint
+	gets map[string]int
+	// posts holds the last body posted per endpoint
+	posts map[string]string
+}
+
+func newFakeRequester(t *testing.T, resources map[string]string) *fakeRequester {
+	return &fakeRequester{t: t, resources: resources, logs: map[string]string{}, gets: map[string]int{}, posts: map[string]string{}}
+}
+
+func (f *fakeRequester) Get(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+	f.gets[endpoint]++
+	if text, ok := response.(*string); ok {
+		log, found := f.logs[endpoint]
+		if !found {
+			return &http.Response{StatusCode: 404, Header: http.Header{}}, nil
+		}
+		start, _ := strconv.Atoi(query["start"])
+		*text = log[start:]
+		return &http.Response{StatusCode: 200, Header: http.Header{"X-Text-Size": {strconv.Itoa(len(log))}}}, nil
+	}
+
+	data, ok := f.resources[endpoint]
+	if !ok {
+		return &http.Response{StatusCode: 404}, errors.New("invalid character '<' looking for beginning of value")
+	}
+	if limit, paged := query["limit"]; paged {
+		var items []json.RawMessage
+		require.NoError(f.t, json.Unmarshal([]byte(data), &items))
+		start, _ := strconv.Atoi(query["start"])
+		end, _ := strconv.Atoi(limit)
+		end = min(start+end, len(items))
+		page, err := json.Marshal(items[min(start, end):end])
+		require.NoError(f.t, err)
+		data = string(page)
+	}
+	require.NoError(f.t, json.Unmarshal([]byte(data), response))
+	return &http.Response{StatusCode: 200}, nil
+}
+
+func (f *fakeRequester) PostJSONBody(ctx context.Context, endpoint string, payload io.Reader, response interface{}) (*http.Response, error) {
+	body, err := io.ReadAll(payload)
+	require.NoError(f.t, err)
+	f.posts[endpoint] = string(body)
+	data, ok := f.resources[endpoint]
+	if !ok {
+		data = "{}"
+	}
+	require.NoError(f.t, json.Unmarshal([]byte(data), response))
+	return &http.Response{StatusCode: 200}, nil
+}
+
+var errUnused = errors.New("not used by blueocean")
+
+func (f *fakeRequester) GetJSON(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+	return nil, errUnused
+}
+
+func (f *fakeRequester) Post(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+	return nil, errUnused
+}
+
+func (f *fakeRequester) PostXML(ctx context.Context, endpoint string, xml string, response interface{}, query map[string]string) (*http.Response, error) {
+	return nil, errUnused
+}
+
+func (f *fakeRequester) PostJSON(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+	return nil, errUnused
+}
+
+func (f *fakeRequester) PostFiles(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string, files []string) (*http.Response, error) {
+	return nil, errUnused
+}
+
+func (f *fakeRequester) GetXML(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+	return nil, errUnused
+}
+
+func TestClient_List(t *testing.T) {
+	fake := newFakeRequester(t, map[string]string{"/items/": `[1, 2, 3, 4, 5]`})
+	client := New(fake)
+	client.PageSize = 2
+
+	var items []string
+	add := func(raw json.RawMessage) error {
+		items = append(items, string(raw))
+		return nil
+	}
+	require.NoError(t, client.list(context.Background(), "/items/", 0, add))
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, items)
+	assert.Equal(t, 3, fake.gets["/items/"])
+
+	items = nil
+	require.NoError(t, client.list(context.Background(), "/items/", 3, add))
+	assert.Equal(t, []string{"1", "2", "3"}, items)
+	assert.Equal(t, 5, fake.gets["/items/"])
+}
+
+func TestClient_NotFound(t *testing.T) {
+	client := New(newFakeRequester(t, map[string]string{}))
+	_, err := client.Pipeline(context.Background(), "missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.EqualError(t, err, org+"/pipelines/missing/: not found")
+}
+
+func TestPipelinePath(t *testing.T) {
+	assert.Equal(t, "/pipelines/team/pipelines/my%20app", pipelinePath("/team/my app"))
+}
+
+func TestTime(t *testing.T) {
+	var v struct {
+		Start Time `json:"start"`
+		End   Time `json:"end"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"start": "2024-03-01T10:15:30.123+0100", "end": null}`), &v))
+	assert.True(t, v.Start.Equal(time.Date(2024, 3, 1, 9, 15, 30, 123000000, time.UTC)))
+	assert.True(t, v.End.IsZero())
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"start": "2024-03-01T10:15:30.123+0100", "end": null}`, string(data))
+}
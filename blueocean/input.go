@@ -0,0 +1,93 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Input is the input a paused step waits for.
+type Input struct {
+	ID         string           `json:"id"`
+	Message    string           `json:"message"`
+	OK         string           `json:"ok"`
+	Submitter  string           `json:"submitter"`
+	Parameters []InputParameter `json:"parameters"`
+}
+
+// InputParameter is a parameter asked for by an input.
+type InputParameter struct {
+	Type         string   `json:"type"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Choices      []string `json:"choices,omitempty"`
+	DefaultValue struct {
+		Value interface{} `json:"value"`
+	} `json:"defaultParameterValue"`
+}
+
+// PendingInputs returns the steps of the run waiting for input.
+func (r *Run) PendingInputs(ctx context.Context) ([]*Step, error) {
+	steps, err := r.Steps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]*Step, 0)
+	for _, s := range steps {
+		if s.Input != nil && s.State == StatePaused {
+			pending = append(pending, s)
+		}
+	}
+	return pending, nil
+}
+
+// SubmitInput answers the input of the step with values for its parameters.
+// Parameters left out take their default value.
+func (s *Step) SubmitInput(ctx context.Context, params map[string]interface{}) error {
+	if s.Input == nil {
+		return errors.New("step " + s.ID + " does not wait for input")
+	}
+	known := make(map[string]bool, len(s.Input.Parameters))
+	for _, p := range s.Input.Parameters {
+		known[p.Name] = true
+	}
+	type inputValue struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	}
+	values := make([]inputValue, 0, len(params))
+	for name, value := range params {
+		if !known[name] {
+			return fmt.Errorf("input %s has no parameter %q", s.Input.ID, name)
+		}
+		values = append(values, inputValue{Name: name, Value: value})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+
+	payload := map[string]interface{}{"id": s.Input.ID, "parameters": values}
+	return s.client.post(ctx, s.base+"/", payload, nil)
+}
+
+// AbortInput aborts the input of the step, which fails the run.
+func (s *Step) AbortInput(ctx context.Context) error {
+	if s.Input == nil {
+		return errors.New("step " + s.ID + " does not wait for input")
+	}
+	payload := map[string]interface{}{"id": s.Input.ID, "abort": true}
+	return s.client.post(ctx, s.base+"/", payload, nil)
+}
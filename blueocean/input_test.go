@@ -0,0 +1,59 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_PendingInputs(t *testing.T) {
+	p, fake := newTestPipeline(t, map[string]string{
+		appPath + "/runs/5/": `{"id": "5", "state": "PAUSED"}`,
+		appPath + "/runs/5/steps/": `[
+			{"id": "7", "displayName": "Shell Script", "state": "FINISHED", "result": "SUCCESS"},
+			{"id": "9", "displayName": "Wait for interactive input", "state": "PAUSED",
+				"input": {"id": "Deploy", "message": "Deploy to?", "ok": "Deploy", "submitter": "ops",
+					"parameters": [{"type": "ChoiceParameterDefinition", "name": "TARGET", "choices": ["staging", "prod"],
+						"defaultParameterValue": {"name": "TARGET", "value": "staging"}}]}}]`,
+	})
+	run, err := p.Run(context.Background(), "5")
+	require.NoError(t, err)
+
+	pending, err := run.PendingInputs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(pending))
+	step := pending[0]
+	assert.Equal(t, "ops", step.Input.Submitter)
+	assert.Equal(t, []string{"staging", "prod"}, step.Input.Parameters[0].Choices)
+	assert.Equal(t, "staging", step.Input.Parameters[0].DefaultValue.Value)
+
+	require.NoError(t, step.SubmitInput(context.Background(), map[string]interface{}{"TARGET": "prod"}))
+	assert.JSONEq(t, `{"id": "Deploy", "parameters": [{"name": "TARGET", "value": "prod"}]}`, fake.posts[appPath+"/runs/5/steps/9/"])
+
+	err = step.SubmitInput(context.Background(), map[string]interface{}{"REGION": "eu"})
+	assert.EqualError(t, err, `input Deploy has no parameter "REGION"`)
+
+	require.NoError(t, step.AbortInput(context.Background()))
+	assert.JSONEq(t, `{"id": "Deploy", "abort": true}`, fake.posts[appPath+"/runs/5/steps/9/"])
+}
+
+func TestStep_SubmitInput_NoInput(t *testing.T) {
+	step := &Step{ID: "7"}
+	assert.EqualError(t, step.SubmitInput(context.Background(), nil), "step 7 does not wait for input")
+}
@@ -0,0 +1,126 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// Types of nodes and steps.
+const (
+	NodeStage    = "STAGE"
+	NodeParallel = "PARALLEL"
+	NodeStep     = "STEP"
+)
+
+// Node is a stage or parallel branch of a run. Edges point to the nodes
+// that follow it; the branches of a parallel stage are all edges of it.
+type Node struct {
+	ID                 string `json:"id"`
+	DisplayName        string `json:"displayName"`
+	DisplayDescription string `json:"displayDescription"`
+	Type               string `json:"type"`
+	Result             string `json:"result"`
+	State              string `json:"state"`
+	StartTime          Time   `json:"startTime"`
+	Duration           int64  `json:"durationInMillis"`
+	FirstParent        string `json:"firstParent"`
+	Edges              []Edge `json:"edges"`
+	Input              *Input `json:"input"`
+
+	client *Client
+	base   string
+}
+
+// Edge links a node to a following one.
+type Edge struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// Step is a step of a node or run.
+type Step struct {
+	ID                 string `json:"id"`
+	DisplayName        string `json:"displayName"`
+	DisplayDescription string `json:"displayDescription"`
+	Type               string `json:"type"`
+	Result             string `json:"result"`
+	State              string `json:"state"`
+	StartTime          Time   `json:"startTime"`
+	Duration           int64  `json:"durationInMillis"`
+	// Input is set while the step waits for input.
+	Input *Input `json:"input"`
+
+	client *Client
+	base   string
+}
+
+// Nodes returns the stages and parallel branches of the run. Nodes that did
+// not start yet are included, with state null.
+func (r *Run) Nodes(ctx context.Context) ([]*Node, error) {
+	nodes := make([]*Node, 0)
+	err := r.client.list(ctx, r.base()+"/nodes/", 0, func(raw json.RawMessage) error {
+		n := &Node{client: r.client}
+		if err := json.Unmarshal(raw, n); err != nil {
+			return err
+		}
+		n.base = r.base() + "/nodes/" + url.PathEscape(n.ID)
+		nodes = append(nodes, n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Steps returns all the steps of the run.
+func (r *Run) Steps(ctx context.Context) ([]*Step, error) {
+	return r.client.steps(ctx, r.base()+"/steps")
+}
+
+// Log reads the log of the steps of the node from offset start.
+func (n *Node) Log(ctx context.Context, start int64) (*Log, error) {
+	return n.client.log(ctx, n.base+"/log/", start)
+}
+
+// Steps returns the steps of the node.
+func (n *Node) Steps(ctx context.Context) ([]*Step, error) {
+	return n.client.steps(ctx, n.base+"/steps")
+}
+
+// Log reads the log of the step from offset start.
+func (s *Step) Log(ctx context.Context, start int64) (*Log, error) {
+	return s.client.log(ctx, s.base+"/log/", start)
+}
+
+func (c *Client) steps(ctx context.Context, base string) ([]*Step, error) {
+	steps := make([]*Step, 0)
+	err := c.list(ctx, base+"/", 0, func(raw json.RawMessage) error {
+		s := &Step{client: c}
+		if err := json.Unmarshal(raw, s); err != nil {
+			return err
+		}
+		s.base = base + "/" + url.PathEscape(s.ID)
+		steps = append(steps, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Nodes(t *testing.T) {
+	p, fake := newTestPipeline(t, map[string]string{
+		appPath + "/runs/4/": `{"id": "4", "state": "RUNNING"}`,
+		appPath + "/runs/4/nodes/": `[
+			{"id": "6", "displayName": "Build", "type": "STAGE", "state": "FINISHED", "result": "SUCCESS", "edges": [{"id": "12", "type": "STAGE"}]},
+			{"id": "12", "displayName": "Test", "type": "STAGE", "state": "RUNNING", "edges": [{"id": "15", "type": "PARALLEL"}, {"id": "16", "type": "PARALLEL"}]},
+			{"id": "15", "displayName": "unit", "type": "PARALLEL", "firstParent": "12", "state": "RUNNING"},
+			{"id": "16", "displayName": "integration", "type": "PARALLEL", "firstParent": "12", "state": null}]`,
+		appPath + "/runs/4/nodes/15/steps/": `[{"id": "18", "displayName": "Shell Script", "displayDescription": "go test ./...", "type": "STEP", "state": "RUNNING"}]`,
+	})
+	fake.logs[appPath+"/runs/4/nodes/15/steps/18/log/"] = "ok  \tgithub.com/bndr/gojenkins\n"
+
+	run, err := p.Run(context.Background(), "4")
+	require.NoError(t, err)
+	nodes, err := run.Nodes(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, len(nodes))
+	assert.Equal(t, NodeParallel, nodes[1].Edges[0].Type)
+	assert.Equal(t, "", nodes[3].State)
+
+	steps, err := nodes[2].Steps(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(steps))
+	assert.Equal(t, "go test ./...", steps[0].DisplayDescription)
+
+	log, err := steps[0].Log(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "ok  \tgithub.com/bndr/gojenkins\n", log.Text)
+}
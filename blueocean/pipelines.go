@@ -0,0 +1,155 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Pipeline is a pipeline job, a multibranch pipeline, one of its branches
+// or a folder. Durations are in milliseconds.
+type Pipeline struct {
+	Class             string `json:"_class"`
+	Name              string `json:"name"`
+	FullName          string `json:"fullName"`
+	DisplayName       string `json:"displayName"`
+	Organization      string `json:"organization"`
+	WeatherScore      int    `json:"weatherScore"`
+	EstimatedDuration int64  `json:"estimatedDurationInMillis"`
+	LatestRun         *Run   `json:"latestRun"`
+
+	// set for multibranch pipelines
+	BranchNames                []string `json:"branchNames,omitempty"`
+	NumberOfFailingBranches    int      `json:"numberOfFailingBranches,omitempty"`
+	NumberOfSuccessfulBranches int      `json:"numberOfSuccessfulBranches,omitempty"`
+
+	// set for branches
+	BranchInfo  *Branch      `json:"branch,omitempty"`
+	PullRequest *PullRequest `json:"pullRequest,omitempty"`
+
+	client *Client
+	base   string
+}
+
+// Branch describes the source branch of a branch pipeline.
+type Branch struct {
+	URL       string `json:"url"`
+	IsPrimary bool   `json:"isPrimary"`
+}
+
+// PullRequest describes the pull request a branch pipeline builds.
+type PullRequest struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Author string `json:"author"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// IsMultiBranch returns true for multibranch pipelines, whose runs belong
+// to their branches.
+func (p *Pipeline) IsMultiBranch() bool {
+	return strings.HasSuffix(p.Class, "MultiBranchPipelineImpl") || len(p.BranchNames) > 0
+}
+
+func (c *Client) newPipeline(raw json.RawMessage, base string) (*Pipeline, error) {
+	p := &Pipeline{client: c, base: base}
+	if err := json.Unmarshal(raw, p); err != nil {
+		return nil, err
+	}
+	if p.LatestRun != nil {
+		p.LatestRun.client = c
+		p.LatestRun.pipelineBase = base
+	}
+	return p, nil
+}
+
+// Pipelines returns the pipelines and folders at the top of the organization.
+func (c *Client) Pipelines(ctx context.Context) ([]*Pipeline, error) {
+	pipelines := make([]*Pipeline, 0)
+	err := c.list(ctx, c.base()+"/pipelines/", 0, func(raw json.RawMessage) error {
+		var name struct {
+			FullName string `json:"fullName"`
+		}
+		if err := json.Unmarshal(raw, &name); err != nil {
+			return err
+		}
+		p, err := c.newPipeline(raw, c.base()+pipelinePath(name.FullName))
+		if err != nil {
+			return err
+		}
+		pipelines = append(pipelines, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pipelines, nil
+}
+
+// Pipeline returns the pipeline with the given full name, with folders
+// separated by slashes, e.g. "team/app".
+func (c *Client) Pipeline(ctx context.Context, fullName string) (*Pipeline, error) {
+	base := c.base() + pipelinePath(fullName)
+	var raw json.RawMessage
+	if err := c.get(ctx, base+"/", &raw, nil); err != nil {
+		return nil, err
+	}
+	return c.newPipeline(raw, base)
+}
+
+// Branches returns the branches of a multibranch pipeline.
+func (p *Pipeline) Branches(ctx context.Context) ([]*Pipeline, error) {
+	branches := make([]*Pipeline, 0)
+	err := p.client.list(ctx, p.base+"/branches/", 0, func(raw json.RawMessage) error {
+		var name struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw, &name); err != nil {
+			return err
+		}
+		b, err := p.client.newPipeline(raw, branchPath(p.base, name.Name))
+		if err != nil {
+			return err
+		}
+		branches = append(branches, b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+// Branch returns a branch of a multibranch pipeline by its name, e.g.
+// "feature/login" or "PR-12".
+func (p *Pipeline) Branch(ctx context.Context, name string) (*Pipeline, error) {
+	base := branchPath(p.base, name)
+	var raw json.RawMessage
+	if err := p.client.get(ctx, base+"/", &raw, nil); err != nil {
+		return nil, err
+	}
+	return p.client.newPipeline(raw, base)
+}
+
+// branchPath returns the path of a branch. Blue Ocean expects branch names
+// encoded twice, so that "feature/login" becomes "feature%252Flogin".
+func branchPath(base, name string) string {
+	return base + "/branches/" + url.PathEscape(url.PathEscape(name))
+}
@@ -0,0 +1,69 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Pipelines(t *testing.T) {
+	client := New(newFakeRequester(t, map[string]string{
+		org + "/pipelines/": `[
+			{"_class": "io.jenkins.blueocean.rest.impl.pipeline.PipelineImpl", "name": "app", "fullName": "app", "weatherScore": 80},
+			{"_class": "io.jenkins.blueocean.service.embedded.rest.PipelineFolderImpl", "name": "team", "fullName": "team"}]`,
+	}))
+	pipelines, err := client.Pipelines(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(pipelines))
+	assert.Equal(t, 80, pipelines[0].WeatherScore)
+	assert.Equal(t, org+"/pipelines/team", pipelines[1].base)
+}
+
+func TestPipeline_Branches(t *testing.T) {
+	fake := newFakeRequester(t, map[string]string{
+		org + "/pipelines/team/pipelines/app/": `{"_class": "io.jenkins.blueocean.rest.impl.pipeline.MultiBranchPipelineImpl",
+			"name": "app", "fullName": "team/app", "branchNames": ["main", "feature/login"], "numberOfFailingBranches": 1}`,
+		org + "/pipelines/team/pipelines/app/branches/": `[
+			{"name": "main", "branch": {"isPrimary": true}, "latestRun": {"id": "12", "result": "SUCCESS", "state": "FINISHED"}},
+			{"name": "feature/login", "branch": {"isPrimary": false}}]`,
+		org + "/pipelines/team/pipelines/app/branches/PR-3/": `{"name": "PR-3", "pullRequest": {"id": "3", "title": "Fix login", "author": "dev"}}`,
+	})
+	fake.logs[org+"/pipelines/team/pipelines/app/branches/main/runs/12/log/"] = "done\n"
+	client := New(fake)
+
+	app, err := client.Pipeline(context.Background(), "team/app")
+	require.NoError(t, err)
+	assert.True(t, app.IsMultiBranch())
+	assert.Equal(t, 1, app.NumberOfFailingBranches)
+
+	branches, err := app.Branches(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(branches))
+	assert.True(t, branches[0].BranchInfo.IsPrimary)
+	// branch names are encoded twice
+	assert.Equal(t, org+"/pipelines/team/pipelines/app/branches/feature%252Flogin", branches[1].base)
+
+	log, err := branches[0].LatestRun.Log(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "done\n", log.Text)
+
+	pr, err := app.Branch(context.Background(), "PR-3")
+	require.NoError(t, err)
+	assert.Equal(t, "Fix login", pr.PullRequest.Title)
+}
@@ -0,0 +1,169 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// Results of runs, nodes and steps.
+const (
+	ResultSuccess  = "SUCCESS"
+	ResultUnstable = "UNSTABLE"
+	ResultFailure  = "FAILURE"
+	ResultNotBuilt = "NOT_BUILT"
+	ResultAborted  = "ABORTED"
+	ResultUnknown  = "UNKNOWN"
+)
+
+// States of runs, nodes and steps.
+const (
+	StateQueued   = "QUEUED"
+	StateRunning  = "RUNNING"
+	StatePaused   = "PAUSED"
+	StateSkipped  = "SKIPPED"
+	StateNotBuilt = "NOT_BUILT"
+	StateFinished = "FINISHED"
+)
+
+// Run is a run of a pipeline or branch. Durations are in milliseconds.
+type Run struct {
+	Class             string   `json:"_class"`
+	ID                string   `json:"id"`
+	Pipeline          string   `json:"pipeline"`
+	Organization      string   `json:"organization"`
+	Result            string   `json:"result"`
+	State             string   `json:"state"`
+	EnQueueTime       Time     `json:"enQueueTime"`
+	StartTime         Time     `json:"startTime"`
+	EndTime           Time     `json:"endTime"`
+	Duration          int64    `json:"durationInMillis"`
+	EstimatedDuration int64    `json:"estimatedDurationInMillis"`
+	RunSummary        string   `json:"runSummary"`
+	CommitID          string   `json:"commitId"`
+	Causes            []Cause  `json:"causes"`
+	ChangeSet         []Change `json:"changeSet"`
+
+	client       *Client
+	pipelineBase string
+}
+
+// Cause is a cause of a run.
+type Cause struct {
+	ShortDescription string `json:"shortDescription"`
+}
+
+// Change is a commit built by a run.
+type Change struct {
+	CommitID  string `json:"commitId"`
+	Message   string `json:"msg"`
+	Timestamp Time   `json:"timestamp"`
+	URL       string `json:"url"`
+	Author    struct {
+		ID       string `json:"id"`
+		FullName string `json:"fullName"`
+	} `json:"author"`
+}
+
+// TestSummary counts the tests of a run. Regressions are tests that passed
+// in the previous run, ExistingFailed tests that already failed in it.
+type TestSummary struct {
+	Total          int `json:"total"`
+	Passed         int `json:"passed"`
+	Failed         int `json:"failed"`
+	Skipped        int `json:"skipped"`
+	Fixed          int `json:"fixed"`
+	ExistingFailed int `json:"existingFailed"`
+	Regressions    int `json:"regressions"`
+}
+
+// IsRunning returns true until the run has finished.
+func (r *Run) IsRunning() bool {
+	return r.State != StateFinished && r.State != StateNotBuilt && r.State != StateSkipped
+}
+
+func (r *Run) base() string {
+	return r.pipelineBase + "/runs/" + url.PathEscape(r.ID)
+}
+
+func (p *Pipeline) newRun(raw json.RawMessage) (*Run, error) {
+	r := &Run{client: p.client, pipelineBase: p.base}
+	if err := json.Unmarshal(raw, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Runs returns the runs of the pipeline, newest first, reading at most max
+// runs, or all of them if max is 0.
+func (p *Pipeline) Runs(ctx context.Context, max int) ([]*Run, error) {
+	runs := make([]*Run, 0)
+	err := p.client.list(ctx, p.base+"/runs/", max, func(raw json.RawMessage) error {
+		r, err := p.newRun(raw)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Run returns the run of the pipeline with the given ID.
+func (p *Pipeline) Run(ctx context.Context, id string) (*Run, error) {
+	var raw json.RawMessage
+	if err := p.client.get(ctx, p.base+"/runs/"+url.PathEscape(id)+"/", &raw, nil); err != nil {
+		return nil, err
+	}
+	return p.newRun(raw)
+}
+
+// Poll refreshes the run.
+func (r *Run) Poll(ctx context.Context) error {
+	return r.client.get(ctx, r.base()+"/", r, nil)
+}
+
+// Log reads the console log of the run from offset start.
+func (r *Run) Log(ctx context.Context, start int64) (*Log, error) {
+	return r.client.log(ctx, r.base()+"/log/", start)
+}
+
+// TestSummary returns the test counts of the run.
+func (r *Run) TestSummary(ctx context.Context) (*TestSummary, error) {
+	summary := new(TestSummary)
+	if err := r.client.get(ctx, r.base()+"/blueTestSummary/", summary, nil); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// Replay starts a new run with the same Jenkinsfile and returns it, usually
+// still queued.
+func (r *Run) Replay(ctx context.Context) (*Run, error) {
+	var raw json.RawMessage
+	if err := r.client.post(ctx, r.base()+"/replay/", struct{}{}, &raw); err != nil {
+		return nil, err
+	}
+	replayed := &Run{client: r.client, pipelineBase: r.pipelineBase}
+	if err := json.Unmarshal(raw, replayed); err != nil {
+		return nil, err
+	}
+	return replayed, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package blueocean
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const appPath = org + "/pipelines/app"
+
+// newTestPipeline returns the pipeline "app" served by a fake requester.
+func newTestPipeline(t *testing.T, resources map[string]string) (*Pipeline, *fakeRequester) {
+	resources[appPath+"/"] = `{"name": "app", "fullName": "app"}`
+	fake := newFakeRequester(t, resources)
+	p, err := New(fake).Pipeline(context.Background(), "app")
+	require.NoError(t, err)
+	return p, fake
+}
+
+func TestPipeline_Runs(t *testing.T) {
+	p, _ := newTestPipeline(t, map[string]string{
+		appPath + "/runs/": `[
+			{"id": "3", "state": "RUNNING", "result": "UNKNOWN", "startTime": "2024-03-01T10:15:30.123+0000", "endTime": null},
+			{"id": "2", "state": "FINISHED", "result": "FAILURE", "durationInMillis": 5000,
+				"causes": [{"shortDescription": "Started by user admin"}],
+				"changeSet": [{"commitId": "abc123", "msg": "Fix build", "author": {"fullName": "Dev"}}]},
+			{"id": "1", "state": "FINISHED", "result": "SUCCESS"}]`,
+	})
+	runs, err := p.Runs(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(runs))
+	assert.True(t, runs[0].IsRunning())
+	assert.False(t, runs[1].IsRunning())
+	assert.Equal(t, "Started by user admin", runs[1].Causes[0].ShortDescription)
+	assert.Equal(t, "Dev", runs[1].ChangeSet[0].Author.FullName)
+	assert.Equal(t, 2024, runs[0].StartTime.Year())
+}
+
+func TestRun_TestSummaryAndLog(t *testing.T) {
+	p, fake := newTestPipeline(t, map[string]string{
+		appPath + "/runs/2/":                 `{"id": "2", "state": "FINISHED", "result": "UNSTABLE"}`,
+		appPath + "/runs/2/blueTestSummary/": `{"total": 12, "passed": 9, "failed": 2, "skipped": 1, "regressions": 1, "existingFailed": 1}`,
+	})
+	fake.logs[appPath+"/runs/2/log/"] = "Started\nFinished: UNSTABLE\n"
+
+	run, err := p.Run(context.Background(), "2")
+	require.NoError(t, err)
+	summary, err := run.TestSummary(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, TestSummary{Total: 12, Passed: 9, Failed: 2, Skipped: 1, Regressions: 1, ExistingFailed: 1}, *summary)
+
+	log, err := run.Log(context.Background(), 8)
+	require.NoError(t, err)
+	assert.Equal(t, "Finished: UNSTABLE\n", log.Text)
+	assert.Equal(t, int64(27), log.Offset)
+	assert.False(t, log.HasMore)
+}
+
+func TestRun_Replay(t *testing.T) {
+	p, fake := newTestPipeline(t, map[string]string{
+		appPath + "/runs/2/":        `{"id": "2", "state": "FINISHED"}`,
+		appPath + "/runs/2/replay/": `{"id": "3", "state": "QUEUED"}`,
+		appPath + "/runs/3/":        `{"id": "3", "state": "RUNNING"}`,
+	})
+	run, err := p.Run(context.Background(), "2")
+	require.NoError(t, err)
+
+	replayed, err := run.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, fake.posts, appPath+"/runs/2/replay/")
+	assert.Equal(t, StateQueued, replayed.State)
+
+	require.NoError(t, replayed.Poll(context.Background()))
+	assert.Equal(t, StateRunning, replayed.State)
+}
@@ -17,7 +17,9 @@ package gojenkins
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -75,37 +77,43 @@ type generalObj struct {
 	Subdir                  interface{}              `json:"subdir"`
 	TotalCount              int64
 	UrlName                 string
+
+	// the action as received, see GetTypedActions
+	raw json.RawMessage
+}
+
+// ChangeSetItem is a single commit of a changeset as reported by Jenkins.
+type ChangeSetItem struct {
+	AffectedPaths []string `json:"affectedPaths"`
+	Author        struct {
+		AbsoluteUrl string `json:"absoluteUrl"`
+		FullName    string `json:"fullName"`
+	} `json:"author"`
+	AuthorEmail string       `json:"authorEmail"`
+	Comment     string       `json:"comment"`
+	CommitID    string       `json:"commitId"`
+	Date        string       `json:"date"`
+	ID          string       `json:"id"`
+	Msg         string       `json:"msg"`
+	Paths       []ChangePath `json:"paths"`
+	Timestamp   int64        `json:"timestamp"`
 }
 
-// TestResult represents the test results of a build.
-type TestResult struct {
-	Duration  float64 `json:"duration"`
-	Empty     bool    `json:"empty"`
-	FailCount int64   `json:"failCount"`
-	PassCount int64   `json:"passCount"`
-	SkipCount int64   `json:"skipCount"`
-	Suites    []struct {
-		Cases []struct {
-			Age             int64       `json:"age"`
-			ClassName       string      `json:"className"`
-			Duration        float64     `json:"duration"`
-			ErrorDetails    interface{} `json:"errorDetails"`
-			ErrorStackTrace interface{} `json:"errorStackTrace"`
-			FailedSince     int64       `json:"failedSince"`
-			Name            string      `json:"name"`
-			Skipped         bool        `json:"skipped"`
-			SkippedMessage  interface{} `json:"skippedMessage"`
-			Status          string      `json:"status"`
-			Stderr          interface{} `json:"stderr"`
-			Stdout          interface{} `json:"stdout"`
-		} `json:"cases"`
-		Duration  float64     `json:"duration"`
-		ID        interface{} `json:"id"`
-		Name      string      `json:"name"`
-		Stderr    interface{} `json:"stderr"`
-		Stdout    interface{} `json:"stdout"`
-		Timestamp interface{} `json:"timestamp"`
-	} `json:"suites"`
+// ChangePath is a file touched by a commit. EditType is "add", "edit" or
+// "delete", or empty when the SCM does not report it.
+type ChangePath struct {
+	EditType string `json:"editType"`
+	File     string `json:"file"`
+}
+
+// BuildChangeSet is the changeset of one SCM checkout of a build.
+type BuildChangeSet struct {
+	Items     []ChangeSetItem `json:"items"`
+	Kind      string          `json:"kind"`
+	Revisions []struct {
+		Module   string
+		Revision int
+	} `json:"revision"`
 }
 
 // BuildResponse represents the JSON response from the Jenkins API for a build.
@@ -116,72 +124,26 @@ type BuildResponse struct {
 		FileName     string `json:"fileName"`
 		RelativePath string `json:"relativePath"`
 	} `json:"artifacts"`
-	Building  bool   `json:"building"`
-	BuiltOn   string `json:"builtOn"`
-	ChangeSet struct {
-		Items []struct {
-			AffectedPaths []string `json:"affectedPaths"`
-			Author        struct {
-				AbsoluteUrl string `json:"absoluteUrl"`
-				FullName    string `json:"fullName"`
-			} `json:"author"`
-			Comment  string `json:"comment"`
-			CommitID string `json:"commitId"`
-			Date     string `json:"date"`
-			ID       string `json:"id"`
-			Msg      string `json:"msg"`
-			Paths    []struct {
-				EditType string `json:"editType"`
-				File     string `json:"file"`
-			} `json:"paths"`
-			Timestamp int64 `json:"timestamp"`
-		} `json:"items"`
-		Kind      string `json:"kind"`
-		Revisions []struct {
-			Module   string
-			Revision int
-		} `json:"revision"`
-	} `json:"changeSet"`
-	ChangeSets []struct {
-		Items []struct {
-			AffectedPaths []string `json:"affectedPaths"`
-			Author        struct {
-				AbsoluteUrl string `json:"absoluteUrl"`
-				FullName    string `json:"fullName"`
-			} `json:"author"`
-			Comment  string `json:"comment"`
-			CommitID string `json:"commitId"`
-			Date     string `json:"date"`
-			ID       string `json:"id"`
-			Msg      string `json:"msg"`
-			Paths    []struct {
-				EditType string `json:"editType"`
-				File     string `json:"file"`
-			} `json:"paths"`
-			Timestamp int64 `json:"timestamp"`
-		} `json:"items"`
-		Kind      string `json:"kind"`
-		Revisions []struct {
-			Module   string
-			Revision int
-		} `json:"revision"`
-	} `json:"changeSets"`
-	Culprits          []Culprit   `json:"culprits"`
-	Description       interface{} `json:"description"`
-	Duration          float64     `json:"duration"`
-	EstimatedDuration float64     `json:"estimatedDuration"`
-	Executor          interface{} `json:"executor"`
-	DisplayName       string      `json:"displayName"`
-	FullDisplayName   string      `json:"fullDisplayName"`
-	ID                string      `json:"id"`
-	KeepLog           bool        `json:"keepLog"`
-	Number            int64       `json:"number"`
-	QueueID           int64       `json:"queueId"`
-	Result            string      `json:"result"`
-	Timestamp         int64       `json:"timestamp"`
-	URL               string      `json:"url"`
-	MavenArtifacts    interface{} `json:"mavenArtifacts"`
-	MavenVersionUsed  string      `json:"mavenVersionUsed"`
+	Building          bool             `json:"building"`
+	BuiltOn           string           `json:"builtOn"`
+	ChangeSet         BuildChangeSet   `json:"changeSet"`
+	ChangeSets        []BuildChangeSet `json:"changeSets"`
+	Culprits          []Culprit        `json:"culprits"`
+	Description       interface{}      `json:"description"`
+	Duration          float64          `json:"duration"`
+	EstimatedDuration float64          `json:"estimatedDuration"`
+	Executor          interface{}      `json:"executor"`
+	DisplayName       string           `json:"displayName"`
+	FullDisplayName   string           `json:"fullDisplayName"`
+	ID                string           `json:"id"`
+	KeepLog           bool             `json:"keepLog"`
+	Number            int64            `json:"number"`
+	QueueID           int64            `json:"queueId"`
+	Result            string           `json:"result"`
+	Timestamp         int64            `json:"timestamp"`
+	URL               string           `json:"url"`
+	MavenArtifacts    interface{}      `json:"mavenArtifacts"`
+	MavenVersionUsed  string           `json:"mavenVersionUsed"`
 	FingerPrint       []FingerPrintResponse
 	Runs              []struct {
 		Number int64
@@ -262,17 +224,26 @@ func (b *Build) GetConsoleOutput(ctx context.Context) string {
 // GetConsoleOutputFromIndex returns console output starting from a specific byte offset.
 // Useful for streaming logs progressively.
 func (b *Build) GetConsoleOutputFromIndex(ctx context.Context, startID int64) (consoleResponse, error) {
-	strstart := strconv.FormatInt(startID, 10)
-	url := b.Base + "/logText/progressiveText"
+	return progressiveText(ctx, b.Jenkins.Requester, b.Base+"/logText/progressiveText", startID)
+}
+
+// errNoLog is returned by progressiveText when there is no such log.
+var errNoLog = errors.New("no log")
 
+// progressiveText reads a log from a progressiveText endpoint of Jenkins,
+// which reports the offset reached and whether more is to come in headers.
+func progressiveText(ctx context.Context, requester JenkinsRequester, url string, startID int64) (consoleResponse, error) {
 	var console consoleResponse
 
 	querymap := make(map[string]string)
-	querymap["start"] = strstart
-	rsp, err := b.Jenkins.Requester.Get(ctx, url, &console.Content, querymap)
+	querymap["start"] = strconv.FormatInt(startID, 10)
+	rsp, err := requester.Get(ctx, url, &console.Content, querymap)
 	if err != nil {
 		return console, err
 	}
+	if rsp.StatusCode == http.StatusNotFound {
+		return console, errNoLog
+	}
 
 	textSize := rsp.Header.Get("X-Text-Size")
 	console.HasMoreText = len(rsp.Header.Get("X-More-Data")) != 0
@@ -379,14 +350,14 @@ func (b *Build) GetAllFingerPrints(ctx context.Context) []*FingerPrint {
 
 // GetUpstreamJob returns the job that triggered this build.
 func (b *Build) GetUpstreamJob(ctx context.Context) (*Job, error) {
-	causes, err := b.GetCauses(ctx)
+	causes, err := b.GetTypedCauses(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, cause := range causes {
-		if job, ok := cause["upstreamProject"]; ok {
-			return b.Jenkins.GetJob(ctx, job.(string))
+		if project, _, ok := upstreamOf(cause); ok && project != "" {
+			return b.Jenkins.GetJob(ctx, project)
 		}
 	}
 	return nil, errors.New("unable to get an upstream job")
@@ -394,18 +365,13 @@ func (b *Build) GetUpstreamJob(ctx context.Context) (*Job, error) {
 
 // GetUpstreamBuildNumber returns the build number of the upstream build that triggered this build.
 func (b *Build) GetUpstreamBuildNumber(ctx context.Context) (int64, error) {
-	causes, err := b.GetCauses(ctx)
+	causes, err := b.GetTypedCauses(ctx)
 	if err != nil {
 		return 0, err
 	}
 	for _, cause := range causes {
-		if build, ok := cause["upstreamBuild"]; ok {
-			switch t := build.(type) {
-			default:
-				return t.(int64), nil
-			case float64:
-				return int64(t), nil
-			}
+		if _, build, ok := upstreamOf(cause); ok && build != 0 {
+			return build, nil
 		}
 	}
 	return 0, nil
@@ -453,6 +419,7 @@ func (b *Build) GetResultSet(ctx context.Context) (*TestResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	report.link(b)
 
 	return &report, nil
 
@@ -471,9 +438,13 @@ func (b *Build) GetDuration() float64 {
 
 // GetRevision returns the VCS revision (commit hash) associated with the build.
 func (b *Build) GetRevision() string {
-	vcs := b.Raw.ChangeSet.Kind
+	changeSet := b.Raw.ChangeSet
+	if changeSet.Kind == "" && len(b.Raw.ChangeSets) > 0 {
+		// pipelines only fill in changeSets
+		changeSet = b.Raw.ChangeSets[0]
+	}
 
-	switch vcs {
+	switch changeSet.Kind {
 	case "git", "hg":
 		for _, a := range b.Raw.Actions {
 			if a.LastBuiltRevision.SHA1 != "" {
@@ -484,7 +455,14 @@ func (b *Build) GetRevision() string {
 			}
 		}
 	case "svn":
-		return strconv.Itoa(b.Raw.ChangeSet.Revisions[0].Revision)
+		if len(changeSet.Revisions) > 0 {
+			return strconv.Itoa(changeSet.Revisions[0].Revision)
+		}
+	default:
+		// the newest commit of any other SCM
+		if changes := b.Raw.changes(); len(changes) > 0 {
+			return changes[len(changes)-1].CommitID
+		}
 	}
 	return ""
 }
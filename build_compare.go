@@ -0,0 +1,394 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of ArtifactChange.
+const (
+	ArtifactAdded   = "added"
+	ArtifactRemoved = "removed"
+	ArtifactChanged = "changed"
+)
+
+// ValueChange is a named value that differs between two builds. Before or
+// After is empty when the value is missing from that build.
+type ValueChange struct {
+	Name   string `json:"name"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// ArtifactChange is an artifact added, removed or changed. Digests are
+// prefixed with their algorithm, e.g. "md5:" when taken from fingerprints.
+type ArtifactChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// StageChange compares a pipeline stage of two builds. Durations are in
+// milliseconds; a stage missing from a build has an empty status.
+type StageChange struct {
+	Name         string `json:"name"`
+	BeforeStatus string `json:"beforeStatus"`
+	AfterStatus  string `json:"afterStatus"`
+	Before       int64  `json:"beforeMillis"`
+	After        int64  `json:"afterMillis"`
+}
+
+// BuildComparison is the difference between a build and a baseline build.
+// Tests is nil unless both builds have a test report, Stages is empty
+// unless both are pipeline runs.
+type BuildComparison struct {
+	Before         int64            `json:"before"`
+	After          int64            `json:"after"`
+	ResultBefore   string           `json:"resultBefore"`
+	ResultAfter    string           `json:"resultAfter"`
+	DurationBefore int64            `json:"durationBeforeMillis"`
+	DurationAfter  int64            `json:"durationAfterMillis"`
+	NodeBefore     string           `json:"nodeBefore"`
+	NodeAfter      string           `json:"nodeAfter"`
+	RevisionBefore string           `json:"revisionBefore"`
+	RevisionAfter  string           `json:"revisionAfter"`
+	Parameters     []ValueChange    `json:"parameters"`
+	Environment    []ValueChange    `json:"environment"`
+	Commits        []Change         `json:"commits"`
+	Artifacts      []ArtifactChange `json:"artifacts"`
+	Tests          *TestComparison  `json:"tests"`
+	Stages         []StageChange    `json:"stages"`
+}
+
+// CompareBuilds compares build b with the baseline a, usually an earlier
+// build of the same job. Commits are those between the two builds, which
+// requires the builds to have a job. Artifacts are compared by their
+// fingerprints when both builds have one, otherwise they are downloaded
+// and hashed.
+func CompareBuilds(ctx context.Context, a, b *Build) (*BuildComparison, error) {
+	cmp := &BuildComparison{
+		Before:         a.GetBuildNumber(),
+		After:          b.GetBuildNumber(),
+		ResultBefore:   a.GetResult(),
+		ResultAfter:    b.GetResult(),
+		DurationBefore: int64(a.GetDuration()),
+		DurationAfter:  int64(b.GetDuration()),
+		NodeBefore:     a.Raw.BuiltOn,
+		NodeAfter:      b.Raw.BuiltOn,
+		RevisionBefore: a.GetRevision(),
+		RevisionAfter:  b.GetRevision(),
+		Parameters:     diffValues(buildParameters(a), buildParameters(b)),
+		Commits:        make([]Change, 0),
+		Stages:         make([]StageChange, 0),
+	}
+
+	envBefore, err := a.injectedEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	envAfter, err := b.injectedEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmp.Environment = diffValues(envBefore, envAfter)
+
+	if a.Job != nil && b.Job != nil && cmp.Before != cmp.After {
+		older, newer := a, b
+		if cmp.Before > cmp.After {
+			older, newer = b, a
+		}
+		if cmp.Commits, err = newer.ChangesSince(ctx, older); err != nil {
+			return nil, err
+		}
+	}
+
+	if cmp.Artifacts, err = diffArtifacts(ctx, a, b); err != nil {
+		return nil, err
+	}
+
+	reportBefore, err := a.testReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reportAfter, err := b.testReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if reportBefore != nil && reportAfter != nil {
+		cmp.Tests = CompareTestResults(reportBefore, reportAfter)
+	}
+
+	runBefore, err := a.pipelineRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	runAfter, err := b.pipelineRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if runBefore != nil && runAfter != nil {
+		cmp.Stages = diffStages(runBefore.Stages, runAfter.Stages)
+	}
+	return cmp, nil
+}
+
+// injectedEnv returns the variables injected by the EnvInject plugin, or
+// nil when the plugin is not installed.
+func (b *Build) injectedEnv(ctx context.Context) (map[string]string, error) {
+	var envVars struct {
+		EnvMap map[string]string `json:"envMap"`
+	}
+	resp, err := b.Jenkins.Requester.GetJSON(ctx, b.Base+"/injectedEnvVars", &envVars, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	return envVars.EnvMap, err
+}
+
+// pipelineRun returns the pipeline run of the build, or nil when the build
+// is not a pipeline.
+func (b *Build) pipelineRun(ctx context.Context) (*PipelineRun, error) {
+	pr := new(PipelineRun)
+	resp, err := b.Jenkins.Requester.GetJSON(ctx, b.Base+"/wfapi/describe", pr, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pr.update()
+	pr.Job = b.Job
+	return pr, nil
+}
+
+func buildParameters(b *Build) map[string]string {
+	params := make(map[string]string)
+	for _, p := range b.GetParameters() {
+		params[p.Name] = fmt.Sprint(p.Value)
+	}
+	return params
+}
+
+// diffValues returns the values that differ, sorted by name.
+func diffValues(before, after map[string]string) []ValueChange {
+	changes := make([]ValueChange, 0)
+	for name, v := range before {
+		if w, ok := after[name]; !ok || v != w {
+			changes = append(changes, ValueChange{Name: name, Before: v, After: w})
+		}
+	}
+	for name, w := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, ValueChange{Name: name, After: w})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// diffArtifacts compares the artifacts of two builds by relative path.
+func diffArtifacts(ctx context.Context, a, b *Build) ([]ArtifactChange, error) {
+	before := make(map[string]bool)
+	for _, art := range a.Raw.Artifacts {
+		before[art.RelativePath] = true
+	}
+	changes := make([]ArtifactChange, 0)
+	seen := make(map[string]bool)
+	for _, art := range b.Raw.Artifacts {
+		rel := art.RelativePath
+		seen[rel] = true
+		if !before[rel] {
+			changes = append(changes, ArtifactChange{Path: rel, Change: ArtifactAdded})
+			continue
+		}
+		digestBefore, digestAfter := a.fingerprintOf(rel), b.fingerprintOf(rel)
+		if digestBefore == "" || digestAfter == "" {
+			var err error
+			if digestBefore, err = a.artifactSHA256(ctx, rel); err != nil {
+				return nil, err
+			}
+			if digestAfter, err = b.artifactSHA256(ctx, rel); err != nil {
+				return nil, err
+			}
+			digestBefore, digestAfter = "sha256:"+digestBefore, "sha256:"+digestAfter
+		}
+		if digestBefore != digestAfter {
+			changes = append(changes, ArtifactChange{Path: rel, Change: ArtifactChanged, Before: digestBefore, After: digestAfter})
+		}
+	}
+	for _, art := range a.Raw.Artifacts {
+		if !seen[art.RelativePath] {
+			changes = append(changes, ArtifactChange{Path: art.RelativePath, Change: ArtifactRemoved})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// fingerprintOf returns the MD5 Jenkins recorded for an artifact, or ""
+// when the build has no such fingerprint. Fingerprints only hold the file
+// name, so artifacts sharing it with another artifact, e.g. linux/app and
+// darwin/app, are left to be hashed.
+func (b *Build) fingerprintOf(rel string) string {
+	name := path.Base(rel)
+	for _, art := range b.Raw.Artifacts {
+		if art.RelativePath != rel && path.Base(art.RelativePath) == name {
+			return ""
+		}
+	}
+	digest := ""
+	for _, f := range b.Raw.FingerPrint {
+		if f.FileName != name || f.Hash == "" {
+			continue
+		}
+		if digest != "" && digest != "md5:"+f.Hash {
+			return ""
+		}
+		digest = "md5:" + f.Hash
+	}
+	return digest
+}
+
+// diffStages pairs the stages of two runs by name, in the order of the
+// newer run followed by the stages it no longer has.
+func diffStages(before, after []PipelineNode) []StageChange {
+	byName := make(map[string]PipelineNode)
+	for _, s := range before {
+		byName[s.Name] = s
+	}
+	changes := make([]StageChange, 0, len(after))
+	seen := make(map[string]bool)
+	for _, s := range after {
+		seen[s.Name] = true
+		c := StageChange{Name: s.Name, AfterStatus: s.Status, After: s.Duration}
+		if old, ok := byName[s.Name]; ok {
+			c.BeforeStatus, c.Before = old.Status, old.Duration
+		}
+		changes = append(changes, c)
+	}
+	for _, s := range before {
+		if !seen[s.Name] {
+			changes = append(changes, StageChange{Name: s.Name, BeforeStatus: s.Status, Before: s.Duration})
+		}
+	}
+	return changes
+}
+
+// WriteJSON writes the comparison as indented JSON.
+func (c *BuildComparison) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// WriteText writes the comparison as a plain text report, leaving out the
+// sections without differences.
+func (c *BuildComparison) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Build #%d (%s) -> #%d (%s)\n", c.Before, c.ResultBefore, c.After, c.ResultAfter)
+	fmt.Fprintf(&b, "Duration: %s -> %s (%s)\n", millis(c.DurationBefore), millis(c.DurationAfter), signedMillis(c.DurationAfter-c.DurationBefore))
+	if c.NodeBefore != c.NodeAfter {
+		fmt.Fprintf(&b, "Node: %s -> %s\n", orNone(c.NodeBefore), orNone(c.NodeAfter))
+	}
+	if c.RevisionBefore != c.RevisionAfter {
+		fmt.Fprintf(&b, "Revision: %s -> %s\n", orNone(c.RevisionBefore), orNone(c.RevisionAfter))
+	}
+	writeValueChanges(&b, "Parameters", c.Parameters)
+	writeValueChanges(&b, "Environment", c.Environment)
+	if len(c.Commits) > 0 {
+		fmt.Fprintf(&b, "\nCommits (%d):\n", len(c.Commits))
+		for i := range c.Commits {
+			fmt.Fprintf(&b, "  %s %s: %s\n", c.Commits[i].CommitID, c.Commits[i].Author, c.Commits[i].Title())
+		}
+	}
+	if len(c.Artifacts) > 0 {
+		b.WriteString("\nArtifacts:\n")
+		marks := map[string]string{ArtifactAdded: "+", ArtifactRemoved: "-", ArtifactChanged: "~"}
+		for _, a := range c.Artifacts {
+			fmt.Fprintf(&b, "  %s %s\n", marks[a.Change], a.Path)
+		}
+	}
+	if c.Tests != nil {
+		tests := []struct {
+			label string
+			cases []*TestCase
+		}{
+			{"new failure", c.Tests.NewFailures},
+			{"still failing", c.Tests.StillFailing},
+			{"fixed", c.Tests.Fixed},
+			{"newly skipped", c.Tests.NewlySkipped},
+		}
+		header := false
+		for _, t := range tests {
+			for _, tc := range t.cases {
+				if !header {
+					b.WriteString("\nTests:\n")
+					header = true
+				}
+				fmt.Fprintf(&b, "  %s: %s\n", t.label, tc.FullName())
+			}
+		}
+	}
+	if len(c.Stages) > 0 {
+		b.WriteString("\nStages:\n")
+		for _, s := range c.Stages {
+			fmt.Fprintf(&b, "  %s: %s -> %s (%s)", s.Name, millis(s.Before), millis(s.After), signedMillis(s.After-s.Before))
+			if s.BeforeStatus != s.AfterStatus {
+				fmt.Fprintf(&b, " %s -> %s", orNone(s.BeforeStatus), orNone(s.AfterStatus))
+			}
+			b.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeValueChanges(b *strings.Builder, title string, changes []ValueChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s:\n", title)
+	for _, c := range changes {
+		fmt.Fprintf(b, "  %s: %s -> %s\n", c.Name, orNone(c.Before), orNone(c.After))
+	}
+}
+
+func millis(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
+}
+
+func signedMillis(ms int64) string {
+	if ms >= 0 {
+		return "+" + millis(ms)
+	}
+	return millis(ms)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
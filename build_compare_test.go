@@ -0,0 +1,151 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	build811 = `{"number": 811, "result": "SUCCESS", "duration": 60000, "builtOn": "agent-1",
+		"artifacts": [{"relativePath": "dist/app.jar"}, {"relativePath": "dist/old.txt"}, {"relativePath": "dist/same.txt"}],
+		"fingerprint": [{"fileName": "app.jar", "hash": "aaa"}],
+		"actions": [{"_class": "hudson.model.ParametersAction", "parameters": [{"name": "ENV", "value": "prod"}, {"name": "DEBUG", "value": false}]}]}`
+	build812 = `{"number": 812, "result": "FAILURE", "duration": 95000, "builtOn": "agent-2",
+		"artifacts": [{"relativePath": "dist/app.jar"}, {"relativePath": "dist/new.txt"}, {"relativePath": "dist/same.txt"}],
+		"fingerprint": [{"fileName": "app.jar", "hash": "bbb"}],
+		"actions": [{"_class": "hudson.model.ParametersAction", "parameters": [{"name": "ENV", "value": "staging"}, {"name": "DEBUG", "value": false}]}]}`
+)
+
+func newCompareMock(t *testing.T) (*Build, *Build) {
+	jenkins := newMockJenkins()
+	endpoints := map[string]string{
+		"/job/app/811/injectedEnvVars": `{"envMap": {"JAVA_HOME": "/opt/jdk17", "CI": "true"}}`,
+		"/job/app/812/injectedEnvVars": `{"envMap": {"JAVA_HOME": "/opt/jdk21", "CI": "true"}}`,
+		"/job/app/812":                 `{"number": 812, "changeSet": {"kind": "git", "items": [{"commitId": "c812", "author": {"fullName": "Alice"}, "msg": "Bump JDK"}]}}`,
+		"/job/app/811/testReport":      baselineReport,
+		"/job/app/812/testReport":      currentReport,
+		"/job/app/811/wfapi/describe":  `{"id": "811", "stages": [{"name": "Build", "status": "SUCCESS", "durationMillis": 30000}, {"name": "Lint", "status": "SUCCESS", "durationMillis": 1000}]}`,
+		"/job/app/812/wfapi/describe":  `{"id": "812", "stages": [{"name": "Build", "status": "FAILED", "durationMillis": 45000}]}`,
+	}
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		data, ok := endpoints[endpoint]
+		if !ok {
+			return &http.Response{StatusCode: 404}, assert.AnError
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), response))
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		// artifacts contain their path, so same.txt is unchanged
+		_, err := io.WriteString(response.(io.Writer), strings.TrimPrefix(endpoint[strings.Index(endpoint, "/artifact/"):], "/artifact/"))
+		return &http.Response{StatusCode: 200}, err
+	}
+	job := &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/app"}
+	a := &Build{Jenkins: jenkins, Job: job, Raw: new(BuildResponse), Base: "/job/app/811"}
+	b := &Build{Jenkins: jenkins, Job: job, Raw: new(BuildResponse), Base: "/job/app/812"}
+	require.NoError(t, json.Unmarshal([]byte(build811), a.Raw))
+	require.NoError(t, json.Unmarshal([]byte(build812), b.Raw))
+	return a, b
+}
+
+func TestCompareBuilds(t *testing.T) {
+	a, b := newCompareMock(t)
+	cmp, err := CompareBuilds(context.Background(), a, b)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ValueChange{{Name: "ENV", Before: "prod", After: "staging"}}, cmp.Parameters)
+	assert.Equal(t, []ValueChange{{Name: "JAVA_HOME", Before: "/opt/jdk17", After: "/opt/jdk21"}}, cmp.Environment)
+	assert.Equal(t, "agent-2", cmp.NodeAfter)
+	require.Equal(t, 1, len(cmp.Commits))
+	assert.Equal(t, "c812", cmp.Commits[0].CommitID)
+	assert.Equal(t, []ArtifactChange{
+		{Path: "dist/app.jar", Change: ArtifactChanged, Before: "md5:aaa", After: "md5:bbb"},
+		{Path: "dist/new.txt", Change: ArtifactAdded},
+		{Path: "dist/old.txt", Change: ArtifactRemoved},
+	}, cmp.Artifacts)
+	require.NotNil(t, cmp.Tests)
+	assert.Equal(t, "testLogin", cmp.Tests.NewFailures[0].Name)
+	assert.Equal(t, []StageChange{
+		{Name: "Build", BeforeStatus: "SUCCESS", AfterStatus: "FAILED", Before: 30000, After: 45000},
+		{Name: "Lint", BeforeStatus: "SUCCESS", Before: 1000},
+	}, cmp.Stages)
+
+	var text bytes.Buffer
+	require.NoError(t, cmp.WriteText(&text))
+	assert.Contains(t, text.String(), "Build #811 (SUCCESS) -> #812 (FAILURE)\nDuration: 1m0s -> 1m35s (+35s)\nNode: agent-1 -> agent-2\n")
+	assert.Contains(t, text.String(), "\nParameters:\n  ENV: prod -> staging\n")
+	assert.Contains(t, text.String(), "\nCommits (1):\n  c812 Alice: Bump JDK\n")
+	assert.Contains(t, text.String(), "  ~ dist/app.jar\n  + dist/new.txt\n  - dist/old.txt\n")
+	assert.Contains(t, text.String(), "  new failure: app.LoginTest.testLogin\n")
+	assert.Contains(t, text.String(), "  Build: 30s -> 45s (+15s) SUCCESS -> FAILED\n  Lint: 1s -> 0s (-1s) SUCCESS -> (none)\n")
+
+	var out bytes.Buffer
+	require.NoError(t, cmp.WriteJSON(&out))
+	var decoded BuildComparison
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, cmp.Artifacts, decoded.Artifacts)
+	assert.Equal(t, int64(95000), decoded.DurationAfter)
+}
+
+func TestDiffArtifacts_SameFileName(t *testing.T) {
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		// only the darwin binary differs between the builds
+		content := strings.Replace(endpoint, "/job/app/2/artifact/linux", "/job/app/1/artifact/linux", 1)
+		_, err := io.WriteString(response.(io.Writer), content)
+		return &http.Response{StatusCode: 200}, err
+	}
+	newBuild := func(number int64, darwinHash string) *Build {
+		b := &Build{Jenkins: jenkins, Raw: new(BuildResponse), Base: "/job/app/" + strconv.FormatInt(number, 10)}
+		data := `{"artifacts": [{"relativePath": "linux/app"}, {"relativePath": "darwin/app"}],
+			"fingerprint": [{"fileName": "app", "hash": "l1"}, {"fileName": "app", "hash": "` + darwinHash + `"}]}`
+		require.NoError(t, json.Unmarshal([]byte(data), b.Raw))
+		return b
+	}
+
+	changes, err := diffArtifacts(context.Background(), newBuild(1, "d1"), newBuild(2, "d2"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(changes))
+	assert.Equal(t, "darwin/app", changes[0].Path)
+	assert.True(t, strings.HasPrefix(changes[0].Before, "sha256:"))
+}
+
+func TestCompareBuilds_NoPluginData(t *testing.T) {
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		return &http.Response{StatusCode: 404}, assert.AnError
+	}
+	a := &Build{Jenkins: jenkins, Raw: &BuildResponse{Number: 1}, Base: "/job/app/1"}
+	b := &Build{Jenkins: jenkins, Raw: &BuildResponse{Number: 2}, Base: "/job/app/2"}
+
+	cmp, err := CompareBuilds(context.Background(), a, b)
+	require.NoError(t, err)
+	assert.Nil(t, cmp.Tests)
+	assert.Empty(t, cmp.Environment)
+	assert.Empty(t, cmp.Stages)
+	assert.Empty(t, cmp.Commits)
+}
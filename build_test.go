@@ -315,30 +315,7 @@ func TestBuild_GetArtifacts(t *testing.T) {
 func TestBuild_GetRevision_Git(t *testing.T) {
 	build := &Build{
 		Raw: &BuildResponse{
-			ChangeSet: struct {
-				Items []struct {
-					AffectedPaths []string `json:"affectedPaths"`
-					Author        struct {
-						AbsoluteUrl string `json:"absoluteUrl"`
-						FullName    string `json:"fullName"`
-					} `json:"author"`
-					Comment  string `json:"comment"`
-					CommitID string `json:"commitId"`
-					Date     string `json:"date"`
-					ID       string `json:"id"`
-					Msg      string `json:"msg"`
-					Paths    []struct {
-						EditType string `json:"editType"`
-						File     string `json:"file"`
-					} `json:"paths"`
-					Timestamp int64 `json:"timestamp"`
-				} `json:"items"`
-				Kind      string `json:"kind"`
-				Revisions []struct {
-					Module   string
-					Revision int
-				} `json:"revision"`
-			}{
+			ChangeSet: BuildChangeSet{
 				Kind: "git",
 			},
 			Actions: []generalObj{
@@ -0,0 +1,196 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Cause is a typed reason for a build or queue item, decoded by its _class.
+type Cause interface {
+	Class() string
+	Description() string
+}
+
+// CauseBase holds the fields every cause has.
+type CauseBase struct {
+	ClassName        string `json:"_class"`
+	ShortDescription string `json:"shortDescription"`
+}
+
+// Class returns the Java class of the cause.
+func (c *CauseBase) Class() string { return c.ClassName }
+
+// Description returns the text Jenkins shows for the cause.
+func (c *CauseBase) Description() string { return c.ShortDescription }
+
+// UserIdCause is a build started by a user.
+type UserIdCause struct {
+	CauseBase
+	UserID   string `json:"userId"`
+	UserName string `json:"userName"`
+}
+
+// UpstreamCause is a build triggered by another build. UpstreamCauses are
+// the causes of that upstream build, so the whole chain can be walked.
+type UpstreamCause struct {
+	CauseBase
+	UpstreamProject string  `json:"upstreamProject"`
+	UpstreamBuild   int64   `json:"upstreamBuild"`
+	UpstreamURL     string  `json:"upstreamUrl"`
+	UpstreamCauses  []Cause `json:"-"`
+}
+
+// UnmarshalJSON decodes the cause including its nested upstream causes.
+func (c *UpstreamCause) UnmarshalJSON(data []byte) error {
+	type upstreamCause UpstreamCause
+	var raw struct {
+		upstreamCause
+		UpstreamCauses []map[string]interface{} `json:"upstreamCauses"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = UpstreamCause(raw.upstreamCause)
+	c.UpstreamCauses = ParseCauses(raw.UpstreamCauses)
+	return nil
+}
+
+// SCMTriggerCause is a build started by SCM polling or a commit hook.
+type SCMTriggerCause struct {
+	CauseBase
+}
+
+// TimerTriggerCause is a build started by a cron trigger.
+type TimerTriggerCause struct {
+	CauseBase
+}
+
+// RemoteCause is a build started through the remote trigger URL.
+type RemoteCause struct {
+	CauseBase
+	Addr string `json:"addr"`
+	Note string `json:"note"`
+}
+
+// BranchIndexingCause is a build started by multibranch branch indexing.
+type BranchIndexingCause struct {
+	CauseBase
+}
+
+// ReplayCause is a pipeline build replayed from OriginalNumber.
+type ReplayCause struct {
+	CauseBase
+	OriginalNumber int64 `json:"originalNumber"`
+}
+
+// RebuildCause is a build started by the Rebuilder plugin; the upstream
+// fields point at the rebuilt build.
+type RebuildCause struct {
+	UpstreamCause
+}
+
+// RawCause is a cause of a class gojenkins does not know about.
+type RawCause struct {
+	CauseBase
+	Fields map[string]interface{}
+}
+
+// upstreamOf returns the upstream project and build of a cause, including
+// causes of unknown classes carrying upstreamProject and upstreamBuild.
+func upstreamOf(cause Cause) (project string, build int64, ok bool) {
+	switch c := cause.(type) {
+	case *UpstreamCause:
+		return c.UpstreamProject, c.UpstreamBuild, true
+	case *RebuildCause:
+		return c.UpstreamProject, c.UpstreamBuild, true
+	case *RawCause:
+		project, hasProject := c.Fields["upstreamProject"].(string)
+		number, hasBuild := c.Fields["upstreamBuild"].(float64)
+		return project, int64(number), hasProject || hasBuild
+	}
+	return "", 0, false
+}
+
+var causeTypes = map[string]func() Cause{
+	"hudson.model.Cause$UserIdCause":                                        func() Cause { return new(UserIdCause) },
+	"hudson.model.Cause$UpstreamCause":                                      func() Cause { return new(UpstreamCause) },
+	"org.jenkinsci.plugins.workflow.support.steps.build.BuildUpstreamCause": func() Cause { return new(UpstreamCause) },
+	"hudson.triggers.SCMTrigger$SCMTriggerCause":                            func() Cause { return new(SCMTriggerCause) },
+	"hudson.triggers.TimerTrigger$TimerTriggerCause":                        func() Cause { return new(TimerTriggerCause) },
+	"hudson.model.Cause$RemoteCause":                                        func() Cause { return new(RemoteCause) },
+	"jenkins.branch.BranchIndexingCause":                                    func() Cause { return new(BranchIndexingCause) },
+	replayCauseClass:                                                        func() Cause { return new(ReplayCause) },
+	"com.sonyericsson.rebuild.RebuildCause":                                 func() Cause { return new(RebuildCause) },
+}
+
+// ParseCauses converts raw causes, as returned by GetCauses, to typed causes.
+// Causes of unknown classes are returned as *RawCause.
+func ParseCauses(raw []map[string]interface{}) []Cause {
+	causes := make([]Cause, 0, len(raw))
+	for _, m := range raw {
+		causes = append(causes, parseCause(m))
+	}
+	return causes
+}
+
+func parseCause(m map[string]interface{}) Cause {
+	class, _ := m["_class"].(string)
+	desc, _ := m["shortDescription"].(string)
+	fallback := &RawCause{CauseBase: CauseBase{ClassName: class, ShortDescription: desc}, Fields: m}
+	factory, ok := causeTypes[class]
+	if !ok {
+		return fallback
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fallback
+	}
+	cause := factory()
+	if err := json.Unmarshal(data, cause); err != nil {
+		return fallback
+	}
+	return cause
+}
+
+// OriginCauses follows upstream causes back to the causes that started the
+// chain, e.g. the user or the commit behind a pipeline of downstream builds.
+// Upstream causes whose own causes are unknown are kept.
+func OriginCauses(causes []Cause) []Cause {
+	origins := make([]Cause, 0, len(causes))
+	for _, c := range causes {
+		if up, ok := c.(*UpstreamCause); ok && len(up.UpstreamCauses) > 0 {
+			origins = append(origins, OriginCauses(up.UpstreamCauses)...)
+			continue
+		}
+		origins = append(origins, c)
+	}
+	return origins
+}
+
+// GetTypedCauses returns the causes that triggered the build.
+func (b *Build) GetTypedCauses(ctx context.Context) ([]Cause, error) {
+	raw, err := b.GetCauses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCauses(raw), nil
+}
+
+// GetTypedCauses returns the causes that triggered this task.
+func (t *Task) GetTypedCauses() []Cause {
+	return ParseCauses(t.GetCauses())
+}
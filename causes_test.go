@@ -0,0 +1,149 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const upstreamCauses = `[{
+	"_class": "hudson.model.Cause$UpstreamCause",
+	"shortDescription": "Started by upstream project \"deploy\" build number 7",
+	"upstreamBuild": 7,
+	"upstreamProject": "deploy",
+	"upstreamUrl": "job/deploy/",
+	"upstreamCauses": [{
+		"_class": "org.jenkinsci.plugins.workflow.support.steps.build.BuildUpstreamCause",
+		"upstreamBuild": 12,
+		"upstreamProject": "build",
+		"upstreamCauses": [
+			{"_class": "hudson.model.Cause$UserIdCause", "userId": "alice", "userName": "Alice"},
+			{"_class": "hudson.triggers.SCMTrigger$SCMTriggerCause", "shortDescription": "Started by an SCM change"}
+		]
+	}]
+}, {
+	"_class": "com.example.CustomCause",
+	"shortDescription": "Started by magic",
+	"spell": "abracadabra"
+}]`
+
+func TestParseCauses(t *testing.T) {
+	var raw []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(upstreamCauses), &raw))
+
+	causes := ParseCauses(raw)
+	require.Equal(t, 2, len(causes))
+
+	up, ok := causes[0].(*UpstreamCause)
+	require.True(t, ok)
+	assert.Equal(t, "deploy", up.UpstreamProject)
+	assert.Equal(t, int64(7), up.UpstreamBuild)
+	require.Equal(t, 1, len(up.UpstreamCauses))
+	assert.Equal(t, int64(12), up.UpstreamCauses[0].(*UpstreamCause).UpstreamBuild)
+
+	custom, ok := causes[1].(*RawCause)
+	require.True(t, ok)
+	assert.Equal(t, "com.example.CustomCause", custom.Class())
+	assert.Equal(t, "Started by magic", custom.Description())
+	assert.Equal(t, "abracadabra", custom.Fields["spell"])
+
+	origins := OriginCauses(causes)
+	require.Equal(t, 3, len(origins))
+	assert.Equal(t, "alice", origins[0].(*UserIdCause).UserID)
+	assert.IsType(t, &SCMTriggerCause{}, origins[1])
+	assert.IsType(t, &RawCause{}, origins[2])
+}
+
+func TestParseCauses_Types(t *testing.T) {
+	causes := ParseCauses([]map[string]interface{}{
+		{"_class": "hudson.triggers.TimerTrigger$TimerTriggerCause"},
+		{"_class": "hudson.model.Cause$RemoteCause", "addr": "10.0.0.1", "note": "nightly"},
+		{"_class": "jenkins.branch.BranchIndexingCause"},
+		{"_class": replayCauseClass, "originalNumber": float64(3)},
+		{"_class": "com.sonyericsson.rebuild.RebuildCause", "upstreamProject": "app", "upstreamBuild": float64(9)},
+	})
+
+	assert.IsType(t, &TimerTriggerCause{}, causes[0])
+	assert.Equal(t, "nightly", causes[1].(*RemoteCause).Note)
+	assert.IsType(t, &BranchIndexingCause{}, causes[2])
+	assert.Equal(t, int64(3), causes[3].(*ReplayCause).OriginalNumber)
+	assert.Equal(t, int64(9), causes[4].(*RebuildCause).UpstreamBuild)
+}
+
+func TestBuild_GetUpstreamBuildNumber(t *testing.T) {
+	build := &Build{
+		Jenkins: newMockJenkins(),
+		Raw: &BuildResponse{Actions: []generalObj{{Causes: []map[string]interface{}{
+			{"_class": "hudson.model.Cause$UpstreamCause", "upstreamProject": "app", "upstreamBuild": float64(41)},
+		}}}},
+		Base: "/job/test-job/42",
+	}
+
+	number, err := build.GetUpstreamBuildNumber(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(41), number)
+}
+
+func TestBuild_GetUpstreamBuildNumber_OtherCauses(t *testing.T) {
+	causes := map[string]map[string]interface{}{
+		"rebuild": {"_class": "com.sonyericsson.rebuild.RebuildCause", "upstreamProject": "app", "upstreamBuild": float64(9)},
+		"unknown": {"_class": "org.example.CustomTriggerCause", "upstreamProject": "app", "upstreamBuild": float64(9)},
+	}
+	for name, cause := range causes {
+		t.Run(name, func(t *testing.T) {
+			build := &Build{
+				Jenkins: newMockJenkins(),
+				Raw:     &BuildResponse{Actions: []generalObj{{Causes: []map[string]interface{}{cause}}}},
+				Base:    "/job/test-job/42",
+			}
+			number, err := build.GetUpstreamBuildNumber(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, int64(9), number)
+			project, _, ok := upstreamOf(ParseCauses([]map[string]interface{}{cause})[0])
+			assert.True(t, ok)
+			assert.Equal(t, "app", project)
+		})
+	}
+}
+
+func TestBuild_GetUpstreamBuildNumber_Malformed(t *testing.T) {
+	// used to panic on the int64 type assertion
+	build := &Build{
+		Jenkins: newMockJenkins(),
+		Raw: &BuildResponse{Actions: []generalObj{{Causes: []map[string]interface{}{
+			{"_class": "hudson.model.Cause$UpstreamCause", "upstreamBuild": "41"},
+		}}}},
+		Base: "/job/test-job/42",
+	}
+
+	number, err := build.GetUpstreamBuildNumber(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), number)
+}
+
+func TestTask_GetTypedCauses(t *testing.T) {
+	task := &Task{Raw: &taskResponse{Actions: []generalAction{{Causes: []map[string]interface{}{
+		{"_class": "hudson.model.Cause$UserIdCause", "userId": "bob"},
+	}}}}}
+
+	causes := task.GetTypedCauses()
+	require.Equal(t, 1, len(causes))
+	assert.Equal(t, "bob", causes[0].(*UserIdCause).UserID)
+}
@@ -0,0 +1,131 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const changesTree = "number,changeSet[kind,items[affectedPaths,author[fullName,absoluteUrl],authorEmail,comment,commitId,msg,paths[editType,file],timestamp]]," +
+	"changeSets[kind,items[affectedPaths,author[fullName,absoluteUrl],authorEmail,comment,commitId,msg,paths[editType,file],timestamp]]"
+
+// Change is a commit built by a build, the same for every SCM.
+type Change struct {
+	Build       int64        `json:"build,omitempty"`
+	Kind        string       `json:"kind"`
+	CommitID    string       `json:"commitId"`
+	Author      string       `json:"author"`
+	AuthorEmail string       `json:"authorEmail,omitempty"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Message     string       `json:"message"`
+	Paths       []ChangePath `json:"paths"`
+}
+
+// Title returns the first line of the commit message.
+func (c *Change) Title() string {
+	title, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+	return title
+}
+
+func newChange(kind string, item *ChangeSetItem) Change {
+	c := Change{
+		Kind:        kind,
+		CommitID:    item.CommitID,
+		Author:      item.Author.FullName,
+		AuthorEmail: item.AuthorEmail,
+		Message:     item.Comment,
+		Paths:       item.Paths,
+	}
+	if c.CommitID == "" {
+		c.CommitID = item.ID
+	}
+	if c.Message == "" {
+		c.Message = item.Msg
+	}
+	if item.Timestamp > 0 {
+		c.Timestamp = time.UnixMilli(item.Timestamp)
+	}
+	if len(c.Paths) == 0 && len(item.AffectedPaths) > 0 {
+		c.Paths = make([]ChangePath, len(item.AffectedPaths))
+		for i, p := range item.AffectedPaths {
+			c.Paths[i] = ChangePath{File: p}
+		}
+	}
+	return c
+}
+
+// changes merges the changeset of a freestyle build with the changesets of
+// every checkout of a pipeline, leaving out commits seen twice.
+func (r *BuildResponse) changes() []Change {
+	changes := make([]Change, 0)
+	seen := make(map[string]bool)
+	for _, cs := range append([]BuildChangeSet{r.ChangeSet}, r.ChangeSets...) {
+		for i := range cs.Items {
+			c := newChange(cs.Kind, &cs.Items[i])
+			c.Build = r.Number
+			key := cs.Kind + "/" + c.CommitID
+			if c.CommitID != "" && seen[key] {
+				continue
+			}
+			seen[key] = true
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Changes returns the commits built by the build across all of its SCMs.
+func (b *Build) Changes(ctx context.Context) ([]Change, error) {
+	resp := new(BuildResponse)
+	if _, err := b.Jenkins.Requester.GetJSON(ctx, b.Base, resp, map[string]string{"tree": changesTree}); err != nil {
+		return nil, err
+	}
+	return resp.changes(), nil
+}
+
+// ChangesSince returns the commits of the builds after other up to and
+// including this build, oldest first, e.g. for release notes.
+// Builds that have been deleted in between are skipped.
+func (b *Build) ChangesSince(ctx context.Context, other *Build) ([]Change, error) {
+	if b.Job == nil {
+		return nil, errors.New("build has no job")
+	}
+	from, to := other.GetBuildNumber(), b.GetBuildNumber()
+	if from >= to {
+		return nil, fmt.Errorf("build %d is not older than build %d", from, to)
+	}
+	changes := make([]Change, 0)
+	for n := from + 1; n <= to; n++ {
+		resp := new(BuildResponse)
+		r, err := b.Jenkins.Requester.GetJSON(ctx, b.Job.Base+"/"+strconv.FormatInt(n, 10), resp, map[string]string{"tree": changesTree})
+		if r != nil && r.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Number == 0 {
+			resp.Number = n
+		}
+		changes = append(changes, resp.changes()...)
+	}
+	return changes, nil
+}
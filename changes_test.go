@@ -0,0 +1,103 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newChangesMock(t *testing.T, builds map[string]string) *Job {
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		assert.Equal(t, changesTree, query["tree"])
+		data, ok := builds[endpoint]
+		if !ok {
+			return &http.Response{StatusCode: 404}, assert.AnError
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), response))
+		return &http.Response{StatusCode: 200}, nil
+	}
+	return &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/app"}
+}
+
+func TestBuild_Changes(t *testing.T) {
+	job := newChangesMock(t, map[string]string{"/job/app/3": `{"number": 3, "changeSets": [
+		{"kind": "git", "items": [{"commitId": "a1", "author": {"fullName": "Alice"}, "authorEmail": "alice@example.com",
+			"comment": "Fix login\n\nDetails", "msg": "Fix login", "timestamp": 1704153600000,
+			"paths": [{"editType": "edit", "file": "login.go"}]}]},
+		{"kind": "svn", "items": [{"commitId": "42", "author": {"fullName": "Bob"}, "msg": "Bump", "affectedPaths": ["trunk/VERSION"]}]}
+	]}`})
+	build := &Build{Jenkins: job.Jenkins, Job: job, Raw: &BuildResponse{Number: 3}, Base: "/job/app/3"}
+
+	changes, err := build.Changes(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(changes))
+	assert.Equal(t, Change{
+		Build:       3,
+		Kind:        "git",
+		CommitID:    "a1",
+		Author:      "Alice",
+		AuthorEmail: "alice@example.com",
+		Timestamp:   time.UnixMilli(1704153600000),
+		Message:     "Fix login\n\nDetails",
+		Paths:       []ChangePath{{EditType: "edit", File: "login.go"}},
+	}, changes[0])
+	assert.Equal(t, "Fix login", changes[0].Title())
+	assert.Equal(t, "Bump", changes[1].Message)
+	assert.Equal(t, []ChangePath{{File: "trunk/VERSION"}}, changes[1].Paths)
+}
+
+func TestBuild_ChangesSince(t *testing.T) {
+	job := newChangesMock(t, map[string]string{
+		"/job/app/2": `{"number": 2, "changeSet": {"kind": "git", "items": [{"commitId": "b1", "msg": "one"}]}}`,
+		"/job/app/4": `{"number": 4, "changeSet": {"kind": "git", "items": [{"commitId": "d1", "msg": "three"}]},
+			"changeSets": [{"kind": "git", "items": [{"commitId": "d1", "msg": "three"}]}]}`,
+	})
+	older := &Build{Raw: &BuildResponse{Number: 1}}
+	build := &Build{Jenkins: job.Jenkins, Job: job, Raw: &BuildResponse{Number: 4}, Base: "/job/app/4"}
+
+	changes, err := build.ChangesSince(context.Background(), older)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(changes))
+	assert.Equal(t, int64(2), changes[0].Build)
+	assert.Equal(t, "d1", changes[1].CommitID)
+
+	_, err = older.ChangesSince(context.Background(), build)
+	assert.Error(t, err)
+}
+
+func TestBuild_GetRevision_NoSvnRevisions(t *testing.T) {
+	build := &Build{Raw: &BuildResponse{ChangeSet: BuildChangeSet{Kind: "svn"}}}
+	assert.Equal(t, "", build.GetRevision())
+
+	build = &Build{Raw: &BuildResponse{ChangeSets: []BuildChangeSet{
+		{Kind: "perforce", Items: []ChangeSetItem{{CommitID: "100"}, {CommitID: "101"}}},
+	}}}
+	assert.Equal(t, "101", build.GetRevision())
+}
+
+func TestBuild_GetRevision_PipelineSvn(t *testing.T) {
+	var raw BuildResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"changeSets": [{"kind": "svn", "revision": [{"module": "https://svn.local/app", "revision": 812}]}]}`), &raw))
+	build := &Build{Raw: &raw}
+	assert.Equal(t, "812", build.GetRevision())
+}
@@ -0,0 +1,150 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// default poll intervals of StreamConsole
+const (
+	consoleMinInterval = 500 * time.Millisecond
+	consoleMaxInterval = 5 * time.Second
+)
+
+// ConsoleStreamOptions configures Build.StreamConsole.
+type ConsoleStreamOptions struct {
+	// Offset is the byte offset to start from, e.g. the offset returned by
+	// an interrupted StreamConsole call.
+	Offset int64
+	// The poll interval starts at MinInterval and doubles up to MaxInterval
+	// while the log does not grow. Zero values use 500ms and 5s.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+}
+
+// StreamConsole follows the console log of the build like `tail -f`, writing
+// it to w until the build has finished and its log has been fully read.
+// Returns the offset reached, which can be used to resume after an error.
+func (b *Build) StreamConsole(ctx context.Context, w io.Writer, opts *ConsoleStreamOptions) (int64, error) {
+	if opts == nil {
+		opts = &ConsoleStreamOptions{}
+	}
+	minInterval, maxInterval := opts.MinInterval, opts.MaxInterval
+	if minInterval <= 0 {
+		minInterval = consoleMinInterval
+	}
+	if maxInterval < minInterval {
+		maxInterval = max(consoleMaxInterval, minInterval)
+	}
+
+	offset := opts.Offset
+	interval := minInterval
+	for {
+		console, err := b.GetConsoleOutputFromIndex(ctx, offset)
+		if err != nil {
+			return offset, err
+		}
+		if console.Content != "" {
+			if _, err := io.WriteString(w, console.Content); err != nil {
+				return offset, err
+			}
+			interval = minInterval
+		} else {
+			interval = min(interval*2, maxInterval)
+		}
+		offset = console.Offset
+		if !console.HasMoreText {
+			return offset, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return offset, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ConsoleLines follows the console log of the build and sends it line by
+// line, without line endings. The channel is closed when the build has
+// finished, ctx is done or a request fails; use StreamConsole with a
+// LineWriter to see the error.
+func (b *Build) ConsoleLines(ctx context.Context) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		lw := NewLineWriter(func(line string) error {
+			select {
+			case lines <- line:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if _, err := b.StreamConsole(ctx, lw, nil); err == nil {
+			_ = lw.Flush()
+		}
+	}()
+	return lines
+}
+
+// LineWriter is an io.Writer that calls a function for every complete line
+// written to it, so lines split across chunks are seen whole.
+type LineWriter struct {
+	fn      func(line string) error
+	partial []byte
+}
+
+// NewLineWriter returns a LineWriter calling fn for every line, without its
+// line ending.
+func NewLineWriter(fn func(line string) error) *LineWriter {
+	return &LineWriter{fn: fn}
+}
+
+func (lw *LineWriter) Write(p []byte) (int, error) {
+	data := p
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(data[:i])
+		if len(lw.partial) > 0 {
+			line = string(lw.partial) + line
+			lw.partial = lw.partial[:0]
+		}
+		if err := lw.fn(strings.TrimSuffix(line, "\r")); err != nil {
+			return len(p) - len(data), err
+		}
+		data = data[i+1:]
+	}
+	lw.partial = append(lw.partial, data...)
+	return len(p), nil
+}
+
+// Flush passes on the last line when it has no trailing newline.
+func (lw *LineWriter) Flush() error {
+	if len(lw.partial) == 0 {
+		return nil
+	}
+	line := string(lw.partial)
+	lw.partial = lw.partial[:0]
+	return lw.fn(strings.TrimSuffix(line, "\r"))
+}
@@ -0,0 +1,152 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package console post-processes Jenkins console output: it removes hidden
+// console notes, strips or renders ANSI escape codes, parses Timestamper
+// prefixes and splits pipeline logs into stage sections.
+//
+// The functions work on text obtained with Build.GetConsoleOutput or line by
+// line on the output of Build.StreamConsole and Build.ConsoleLines.
+package console
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CSI sequences (colors, cursor movement) and OSC sequences (titles, links)
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// only SGR sequences are rendered, everything else is dropped
+var sgrRegex = regexp.MustCompile(`^\x1b\[([0-9;]*)m$`)
+
+var ansiColors = [8]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// StripANSI removes ANSI escape sequences.
+func StripANSI(s string) string {
+	return ansiRegex.ReplaceAllString(s, "")
+}
+
+// Clean removes console notes and ANSI escape sequences, leaving the text a
+// user would see in the Jenkins UI.
+func Clean(s string) string {
+	return StripANSI(StripNotes(s))
+}
+
+// sgrState is the current text style while rendering.
+type sgrState struct {
+	bold, italic, underline bool
+	fg, bg                  string
+}
+
+func (st sgrState) classes() string {
+	classes := make([]string, 0, 5)
+	if st.bold {
+		classes = append(classes, "ansi-bold")
+	}
+	if st.italic {
+		classes = append(classes, "ansi-italic")
+	}
+	if st.underline {
+		classes = append(classes, "ansi-underline")
+	}
+	if st.fg != "" {
+		classes = append(classes, "ansi-"+st.fg+"-fg")
+	}
+	if st.bg != "" {
+		classes = append(classes, "ansi-"+st.bg+"-bg")
+	}
+	return strings.Join(classes, " ")
+}
+
+// apply updates the style with the parameters of an SGR sequence.
+func (st *sgrState) apply(params string) {
+	if params == "" {
+		params = "0"
+	}
+	for _, p := range strings.Split(params, ";") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			continue
+		}
+		switch {
+		case n == 0:
+			*st = sgrState{}
+		case n == 1:
+			st.bold = true
+		case n == 3:
+			st.italic = true
+		case n == 4:
+			st.underline = true
+		case n == 22:
+			st.bold = false
+		case n == 23:
+			st.italic = false
+		case n == 24:
+			st.underline = false
+		case n >= 30 && n <= 37:
+			st.fg = ansiColors[n-30]
+		case n == 39:
+			st.fg = ""
+		case n >= 40 && n <= 47:
+			st.bg = ansiColors[n-40]
+		case n == 49:
+			st.bg = ""
+		case n >= 90 && n <= 97:
+			st.fg = "bright-" + ansiColors[n-90]
+		case n >= 100 && n <= 107:
+			st.bg = "bright-" + ansiColors[n-100]
+		}
+	}
+}
+
+// RenderANSI converts text with ANSI color codes to HTML. The text is escaped
+// and styled runs are wrapped in spans with classes such as "ansi-bold" and
+// "ansi-red-fg". Console notes should be removed first.
+func RenderANSI(s string) string {
+	var b strings.Builder
+	var st sgrState
+	open := false
+	last := 0
+	write := func(text string) {
+		if text == "" {
+			return
+		}
+		b.WriteString(html.EscapeString(text))
+	}
+	for _, loc := range ansiRegex.FindAllStringIndex(s, -1) {
+		write(s[last:loc[0]])
+		last = loc[1]
+		m := sgrRegex.FindStringSubmatch(s[loc[0]:loc[1]])
+		if m == nil {
+			continue
+		}
+		st.apply(m[1])
+		if open {
+			b.WriteString("</span>")
+			open = false
+		}
+		if classes := st.classes(); classes != "" {
+			b.WriteString(`<span class="` + classes + `">`)
+			open = true
+		}
+	}
+	write(s[last:])
+	if open {
+		b.WriteString("</span>")
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package console
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripANSI(t *testing.T) {
+	assert.Equal(t, "ok done", StripANSI("\x1b[1;32mok\x1b[0m \x1b]0;title\x07done\x1b[K"))
+}
+
+func TestRenderANSI(t *testing.T) {
+	out := RenderANSI("\x1b[1;31mfail <x>\x1b[22m!\x1b[0m ok")
+	assert.Equal(t, `<span class="ansi-bold ansi-red-fg">fail &lt;x&gt;</span><span class="ansi-red-fg">!</span> ok`, out)
+}
+
+func TestClean(t *testing.T) {
+	line := "\x1b[8mha:////4GwBLAAAAAA+uAAA=\x1b[0m[Pipeline] \x1b[34mecho\x1b[0m"
+	assert.Equal(t, "[Pipeline] echo", Clean(line))
+}
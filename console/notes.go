@@ -0,0 +1,27 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package console
+
+import "regexp"
+
+// Console notes are serialized objects Jenkins hides in the log between
+// "\x1b[8mha:" and "\x1b[0m". Once the escape codes are gone only the base64
+// payload remains, which always starts with "////".
+var notesRegex = regexp.MustCompile(`\x1b\[8mha:[^\x1b]*\x1b\[0m|ha:////[A-Za-z0-9+/]*=*`)
+
+// StripNotes removes hidden console notes, with or without their escape codes.
+func StripNotes(s string) string {
+	return notesRegex.ReplaceAllString(s, "")
+}
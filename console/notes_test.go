@@ -0,0 +1,28 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package console
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripNotes(t *testing.T) {
+	assert.Equal(t, "Started by user admin", StripNotes("Started by user \x1b[8mha:////4LkdF+aA/bbb==\x1b[0madmin"))
+	// escape codes already removed by someone else
+	assert.Equal(t, "[Pipeline] stage", StripNotes("ha:////4GwBLAAAAAA+uAAA=[Pipeline] stage"))
+	assert.Equal(t, "no notes here", StripNotes("no notes here"))
+}
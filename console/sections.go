@@ -0,0 +1,125 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package console
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pipeline markers, possibly behind a timestamp
+var (
+	stageStartRegex = regexp.MustCompile(`\[Pipeline\] stage$`)
+	blockOpenRegex  = regexp.MustCompile(`\[Pipeline\] \{(?: \((.*)\))?$`)
+	blockCloseRegex = regexp.MustCompile(`\[Pipeline\] \}$`)
+)
+
+// Section is a run of consecutive console lines of the same stage.
+// Path holds the names of the enclosing stages, outermost first; it is empty
+// for lines outside of any stage. Start is the 1-based number of the first line.
+type Section struct {
+	Path  []string
+	Start int
+	Lines []string
+}
+
+// Name returns the name of the innermost stage, or "" outside of stages.
+func (s *Section) Name() string {
+	if len(s.Path) == 0 {
+		return ""
+	}
+	return s.Path[len(s.Path)-1]
+}
+
+// Splitter tracks the stage blocks of pipeline console output line by line,
+// for use on streamed logs.
+type Splitter struct {
+	// one entry per open block, "" for blocks that are not stages
+	blocks  []string
+	inStage bool
+}
+
+// Add consumes a line and returns the stage path it belongs to. Marker lines
+// opening or closing a stage belong to the enclosing stage.
+func (sp *Splitter) Add(line string) []string {
+	path := sp.Path()
+	switch {
+	case stageStartRegex.MatchString(line):
+		sp.inStage = true
+	case blockOpenRegex.MatchString(line):
+		name := ""
+		if sp.inStage {
+			name = blockOpenRegex.FindStringSubmatch(line)[1]
+		}
+		sp.blocks = append(sp.blocks, name)
+		sp.inStage = false
+	case blockCloseRegex.MatchString(line):
+		if len(sp.blocks) > 0 {
+			sp.blocks = sp.blocks[:len(sp.blocks)-1]
+		}
+		path = sp.Path()
+	}
+	return path
+}
+
+// Path returns the stages enclosing the next line.
+func (sp *Splitter) Path() []string {
+	path := make([]string, 0, len(sp.blocks))
+	for _, b := range sp.blocks {
+		if b != "" {
+			path = append(path, b)
+		}
+	}
+	return path
+}
+
+// Sections splits pipeline console output into stage sections using the
+// "[Pipeline] stage" markers. Output of parallel branches is interleaved in
+// the log and ends up in the enclosing stage.
+func Sections(lines []string) []*Section {
+	sections := make([]*Section, 0)
+	var sp Splitter
+	var current *Section
+	for i, line := range lines {
+		path := sp.Add(line)
+		if current == nil || !samePath(current.Path, path) {
+			current = &Section{Path: path, Start: i + 1}
+			sections = append(sections, current)
+		}
+		current.Lines = append(current.Lines, line)
+	}
+	return sections
+}
+
+// SectionsOf splits console output text into stage sections.
+func SectionsOf(text string) []*Section {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return []*Section{}
+	}
+	return Sections(strings.Split(text, "\n"))
+}
+
+func samePath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package console
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pipelineLog = `Started by user admin
+[Pipeline] Start of Pipeline
+[Pipeline] node
+[Pipeline] {
+[Pipeline] stage
+[Pipeline] { (Build)
+[Pipeline] sh
++ make
+[Pipeline] stage
+[Pipeline] { (Inner)
+inner output
+[Pipeline] }
+[Pipeline] // stage
+[Pipeline] }
+[Pipeline] // stage
+[Pipeline] stage
+[2024-01-02T03:04:05.678Z] [Pipeline] { (Test)
+ok
+[Pipeline] }
+[Pipeline] // stage
+[Pipeline] }
+[Pipeline] End of Pipeline
+Finished: SUCCESS
+`
+
+func TestSectionsOf(t *testing.T) {
+	sections := SectionsOf(pipelineLog)
+	require.Equal(t, 7, len(sections))
+
+	assert.Equal(t, "", sections[0].Name())
+	assert.Equal(t, []string{"Build"}, sections[1].Path)
+	assert.Equal(t, 7, sections[1].Start)
+	assert.Equal(t, []string{"[Pipeline] sh", "+ make", "[Pipeline] stage", "[Pipeline] { (Inner)"}, sections[1].Lines)
+	assert.Equal(t, []string{"Build", "Inner"}, sections[2].Path)
+	assert.Equal(t, []string{"inner output"}, sections[2].Lines)
+	assert.Equal(t, "Build", sections[3].Name())
+	assert.Equal(t, []string{"[Pipeline] }", "[Pipeline] // stage"}, sections[3].Lines)
+	assert.Equal(t, "Test", sections[5].Name())
+	assert.Equal(t, []string{"ok"}, sections[5].Lines)
+	assert.Equal(t, "", sections[6].Name())
+}
@@ -0,0 +1,99 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package console
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Prefixes written by the Timestamper plugin, either in the log itself
+// ("[2024-01-02T03:04:05.678Z] ") or by its /timestamps/?time=...&appendLog
+// endpoint ("2024-01-02T03:04:05.678+0000  "), plus the time of day only
+// variants ("[03:04:05] ") of older builds.
+var timestampRegex = regexp.MustCompile(`^(?:\[([0-9T:.+\-Z ]+)\] |([0-9]{4}-[0-9T:.+\-Z]+|[0-9]{2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]+)?)  ?)`)
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+const clockLayout = "15:04:05.999999999"
+
+// Record is a console line with the time Timestamper recorded for it.
+// Time is zero when the line has no timestamp.
+type Record struct {
+	Time time.Time
+	Line string
+}
+
+// Timestamps parses Timestamper prefixes line by line.
+// Lines with only a time of day are placed on Day, in its location, and move
+// to the next day when the clock wraps around midnight.
+type Timestamps struct {
+	Day  time.Time
+	last time.Time
+}
+
+// Parse splits the timestamp off a line. Lines without a timestamp are
+// returned unchanged with a zero time.
+func (ts *Timestamps) Parse(line string) Record {
+	m := timestampRegex.FindStringSubmatch(line)
+	if m == nil {
+		return Record{Line: line}
+	}
+	value := m[1] + m[2]
+	rest := line[len(m[0]):]
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			ts.last = t
+			return Record{Time: t, Line: rest}
+		}
+	}
+	clock, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return Record{Line: line}
+	}
+	day := ts.Day
+	if !ts.last.IsZero() {
+		day = ts.last
+	}
+	y, mo, d := day.Date()
+	loc := day.Location()
+	t := time.Date(y, mo, d, clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), loc)
+	if !ts.last.IsZero() && t.Before(ts.last) {
+		t = t.AddDate(0, 0, 1)
+	}
+	ts.last = t
+	return Record{Time: t, Line: rest}
+}
+
+// ParseTimestamps reads timestamped console output, placing time of day only
+// timestamps on the day of start, e.g. the start time of the build.
+func ParseTimestamps(r io.Reader, start time.Time) ([]Record, error) {
+	ts := &Timestamps{Day: start}
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		records = append(records, ts.Parse(strings.TrimSuffix(scanner.Text(), "\r")))
+	}
+	return records, scanner.Err()
+}
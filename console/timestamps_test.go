@@ -0,0 +1,53 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestamps_Parse(t *testing.T) {
+	var ts Timestamps
+
+	r := ts.Parse("[2024-01-02T03:04:05.678Z] [Pipeline] stage")
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 678000000, time.UTC), r.Time)
+	assert.Equal(t, "[Pipeline] stage", r.Line)
+
+	r = ts.Parse("2024-01-02T03:04:06.000+0000  + make")
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC), r.Time.UTC())
+	assert.Equal(t, "+ make", r.Line)
+
+	r = ts.Parse("no timestamp")
+	assert.True(t, r.Time.IsZero())
+	assert.Equal(t, "no timestamp", r.Line)
+}
+
+func TestParseTimestamps_ClockOnly(t *testing.T) {
+	start := time.Date(2024, 1, 2, 23, 59, 0, 0, time.UTC)
+	log := "[23:59:58] Started\r\n[00:00:01] Building\nplain\n"
+
+	records, err := ParseTimestamps(strings.NewReader(log), start)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(records))
+	assert.Equal(t, time.Date(2024, 1, 2, 23, 59, 58, 0, time.UTC), records[0].Time)
+	assert.Equal(t, time.Date(2024, 1, 3, 0, 0, 1, 0, time.UTC), records[1].Time)
+	assert.Equal(t, "Building", records[1].Line)
+	assert.True(t, records[2].Time.IsZero())
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConsoleMock serves log as a growing progressiveText, one chunk per request.
+func newConsoleMock(t *testing.T, chunks ...string) *Build {
+	jenkins := newMockJenkins()
+	log := ""
+	served := 0
+	jenkins.Requester.(*MockRequester).GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		assert.Equal(t, "/job/app/1/logText/progressiveText", endpoint)
+		start, _ := strconv.Atoi(query["start"])
+		if served < len(chunks) {
+			log += chunks[served]
+			served++
+		}
+		*response.(*string) = log[start:]
+		header := http.Header{}
+		header.Set("X-Text-Size", strconv.Itoa(len(log)))
+		if served < len(chunks) {
+			header.Set("X-More-Data", "true")
+		}
+		return &http.Response{StatusCode: 200, Header: header}, nil
+	}
+	return &Build{Jenkins: jenkins, Raw: new(BuildResponse), Base: "/job/app/1"}
+}
+
+func TestBuild_StreamConsole(t *testing.T) {
+	build := newConsoleMock(t, "Started\nBuil", "", "ding\n", "Finished: SUCCESS\n")
+
+	var buf bytes.Buffer
+	offset, err := build.StreamConsole(context.Background(), &buf, &ConsoleStreamOptions{MinInterval: time.Millisecond, MaxInterval: time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, "Started\nBuilding\nFinished: SUCCESS\n", buf.String())
+	assert.Equal(t, int64(buf.Len()), offset)
+}
+
+func TestBuild_StreamConsole_Resume(t *testing.T) {
+	build := newConsoleMock(t, "Started\nFinished\n")
+
+	var buf bytes.Buffer
+	_, err := build.StreamConsole(context.Background(), &buf, &ConsoleStreamOptions{Offset: 8})
+	require.NoError(t, err)
+	assert.Equal(t, "Finished\n", buf.String())
+}
+
+func TestBuild_StreamConsole_Cancel(t *testing.T) {
+	build := newConsoleMock(t, "Started\n", "", "", "", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	offset, err := build.StreamConsole(ctx, &buf, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int64(8), offset)
+}
+
+func TestBuild_ConsoleLines(t *testing.T) {
+	build := newConsoleMock(t, "one\r\ntw", "o\nthree")
+
+	lines := make([]string, 0)
+	for line := range build.ConsoleLines(context.Background()) {
+		lines = append(lines, line)
+	}
+	assert.Equal(t, []string{"one", "two", "three"}, lines)
+}
+
+func TestLineWriter_Error(t *testing.T) {
+	stop := errors.New("stop")
+	lw := NewLineWriter(func(line string) error { return stop })
+
+	n, err := lw.Write([]byte("a\nb\n"))
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 0, n)
+}
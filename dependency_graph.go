@@ -0,0 +1,505 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Edge kinds of a JobGraph.
+const (
+	// EdgeTrigger comes from the upstreamProjects/downstreamProjects of a job.
+	EdgeTrigger = "trigger"
+	// EdgeConfig comes from a parameterized trigger or a pipeline build step.
+	EdgeConfig = "config"
+)
+
+// Directions followed when building a JobGraph.
+const (
+	GraphBoth = iota
+	GraphDownstream
+	GraphUpstream
+)
+
+var (
+	triggerProjectsRegex = regexp.MustCompile(`<projects>([^<]*)</projects>`)
+	buildStepRegex       = regexp.MustCompile(`\bbuild\s*(?:\(\s*)?(?:job\s*:\s*)?` + configQuote + `(.*?)` + configQuote)
+)
+
+// JobGraphNode is a job in a JobGraph, keyed by its full name.
+type JobGraphNode struct {
+	FullName string `json:"fullName"`
+	Class    string `json:"_class"`
+	URL      string `json:"url"`
+	Color    string `json:"color"`
+	// Unresolved is set when the job could not be fetched, e.g. because it
+	// was deleted while still listed as a dependency of another job.
+	Unresolved bool `json:"unresolved,omitempty"`
+}
+
+// JobEdge means that a build of From triggers a build of To.
+type JobEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// JobGraph is a directed graph of job dependencies.
+type JobGraph struct {
+	Nodes map[string]*JobGraphNode
+	Edges []JobEdge
+	seen  map[[2]string]bool
+}
+
+// DependencyGraphOptions controls how BuildDependencyGraph walks the jobs.
+type DependencyGraphOptions struct {
+	// Direction is one of GraphBoth, GraphDownstream or GraphUpstream.
+	Direction int
+	// ScanConfig also adds edges from parameterized trigger and pipeline
+	// build steps found in each job's config.xml. It is ignored with
+	// GraphUpstream, as finding the jobs whose config triggers a job would
+	// mean reading every config on the controller.
+	ScanConfig bool
+}
+
+// ErrDependencyCycle is returned by TopologicalOrder when the graph has a cycle.
+type ErrDependencyCycle struct {
+	Cycle []string
+}
+
+func (e *ErrDependencyCycle) Error() string {
+	return "dependency cycle: " + strings.Join(e.Cycle, " -> ")
+}
+
+type graphJobResponse struct {
+	Class              string     `json:"_class"`
+	FullName           string     `json:"fullName"`
+	URL                string     `json:"url"`
+	Color              string     `json:"color"`
+	UpstreamProjects   []InnerJob `json:"upstreamProjects"`
+	DownstreamProjects []InnerJob `json:"downstreamProjects"`
+}
+
+// NewJobGraph returns an empty graph.
+func NewJobGraph() *JobGraph {
+	return &JobGraph{Nodes: make(map[string]*JobGraphNode), Edges: make([]JobEdge, 0), seen: make(map[[2]string]bool)}
+}
+
+// AddEdge adds an edge, ignoring duplicates of an existing From/To pair.
+func (g *JobGraph) AddEdge(from string, to string, kind string) {
+	key := [2]string{from, to}
+	if g.seen[key] {
+		return
+	}
+	g.seen[key] = true
+	for _, name := range key {
+		if _, ok := g.Nodes[name]; !ok {
+			g.Nodes[name] = &JobGraphNode{FullName: name}
+		}
+	}
+	g.Edges = append(g.Edges, JobEdge{From: from, To: to, Kind: kind})
+}
+
+// fullNameFromURL turns a job URL such as http://host/job/a/job/b/ into "a/b".
+func fullNameFromURL(server string, jobURL string) string {
+	p := strings.TrimPrefix(jobURL, server)
+	if u, err := url.Parse(p); err == nil {
+		p = u.Path
+	}
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	names := make([]string, 0, len(parts)/2)
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "job" {
+			name, err := url.PathUnescape(parts[i+1])
+			if err != nil {
+				name = parts[i+1]
+			}
+			names = append(names, name)
+			i++
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// resolveJobName resolves a job reference from a config relative to the folder of the referencing job.
+func resolveJobName(from string, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "/") {
+		return strings.Trim(ref, "/")
+	}
+	parent, _ := splitFullName(from)
+	parts := parentIDs(parent)
+	for _, p := range strings.Split(ref, "/") {
+		switch p {
+		case "", ".":
+		case "..":
+			if len(parts) > 0 {
+				parts = parts[:len(parts)-1]
+			}
+		default:
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// configDependencies returns the jobs triggered from a config.xml.
+func configDependencies(from string, config string) []string {
+	deps := make([]string, 0)
+	for _, m := range triggerProjectsRegex.FindAllStringSubmatch(config, -1) {
+		for _, ref := range strings.Split(m[1], ",") {
+			if strings.TrimSpace(ref) != "" {
+				deps = append(deps, resolveJobName(from, ref))
+			}
+		}
+	}
+	for _, m := range buildStepRegex.FindAllStringSubmatch(config, -1) {
+		if m[1] != "" && !strings.Contains(m[1], "${") {
+			deps = append(deps, resolveJobName(from, m[1]))
+		}
+	}
+	return deps
+}
+
+// DependencyGraph builds the full transitive graph of jobs reachable from
+// roots through their upstream and downstream projects, i.e. the jobs the
+// roots trigger and the jobs triggering them.
+// Example: jenkins.DependencyGraph(ctx, "base-image", "team/api")
+func (j *Jenkins) DependencyGraph(ctx context.Context, roots ...string) (*JobGraph, error) {
+	return j.BuildDependencyGraph(ctx, &DependencyGraphOptions{}, roots...)
+}
+
+// BuildDependencyGraph is DependencyGraph with options. With GraphBoth the
+// descendants and the ancestors of the roots are walked separately, so other
+// jobs triggered by an ancestor are left out. Jobs that cannot be fetched are
+// kept as unresolved nodes and the walk goes on; only transport errors abort
+// it.
+func (j *Jenkins) BuildDependencyGraph(ctx context.Context, opts *DependencyGraphOptions, roots ...string) (*JobGraph, error) {
+	if opts == nil {
+		opts = &DependencyGraphOptions{}
+	}
+	w := &dependencyWalk{jenkins: j, graph: NewJobGraph(), jobs: make(map[string]*graphJobResponse), scanConfig: opts.ScanConfig}
+	directions := []int{opts.Direction}
+	if opts.Direction == GraphBoth {
+		directions = []int{GraphDownstream, GraphUpstream}
+	}
+	for _, direction := range directions {
+		if err := w.walk(ctx, direction, roots); err != nil {
+			return nil, err
+		}
+	}
+	return w.graph, nil
+}
+
+// dependencyWalk builds a JobGraph, fetching every job once.
+type dependencyWalk struct {
+	jenkins    *Jenkins
+	graph      *JobGraph
+	jobs       map[string]*graphJobResponse
+	scanConfig bool
+}
+
+// walk adds the jobs reachable from roots in one direction.
+func (w *dependencyWalk) walk(ctx context.Context, direction int, roots []string) error {
+	queue := make([]string, 0, len(roots))
+	visited := make(map[string]bool)
+	for _, root := range roots {
+		queue = append(queue, strings.Trim(root, "/"))
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+
+		raw, err := w.job(ctx, name)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+		if direction == GraphDownstream {
+			for _, d := range raw.DownstreamProjects {
+				to := fullNameFromURL(w.jenkins.Server, d.Url)
+				w.graph.AddEdge(name, to, EdgeTrigger)
+				queue = append(queue, to)
+			}
+		} else {
+			for _, u := range raw.UpstreamProjects {
+				from := fullNameFromURL(w.jenkins.Server, u.Url)
+				w.graph.AddEdge(from, name, EdgeTrigger)
+				queue = append(queue, from)
+			}
+		}
+		if w.scanConfig && direction == GraphDownstream {
+			deps, err := w.configDependencies(ctx, name)
+			if err != nil {
+				return err
+			}
+			for _, to := range deps {
+				w.graph.AddEdge(name, to, EdgeConfig)
+				queue = append(queue, to)
+			}
+		}
+	}
+	return nil
+}
+
+// job fetches a job and adds it to the graph. It returns nil for a job that
+// cannot be fetched, which is marked as unresolved.
+func (w *dependencyWalk) job(ctx context.Context, name string) (*graphJobResponse, error) {
+	if raw, ok := w.jobs[name]; ok {
+		return raw, nil
+	}
+	node, ok := w.graph.Nodes[name]
+	if !ok {
+		node = &JobGraphNode{FullName: name}
+		w.graph.Nodes[name] = node
+	}
+	qr := map[string]string{
+		"tree": "_class,fullName,url,color,upstreamProjects[name,url],downstreamProjects[name,url]",
+	}
+	raw := new(graphJobResponse)
+	resp, err := w.jenkins.Requester.GetJSON(ctx, itemBase(name), raw, qr)
+	if resp != nil && resp.StatusCode != http.StatusOK {
+		node.Unresolved = true
+		w.jobs[name] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", name, err)
+	}
+	node.Class, node.URL, node.Color = raw.Class, raw.URL, raw.Color
+	w.jobs[name] = raw
+	return raw, nil
+}
+
+// configDependencies returns the jobs triggered from the config of a job.
+// A config that cannot be read, e.g. for lack of permission, has none.
+func (w *dependencyWalk) configDependencies(ctx context.Context, name string) ([]string, error) {
+	var config string
+	resp, err := w.jenkins.Requester.GetXML(ctx, itemBase(name)+"/config.xml", &config, nil)
+	if resp != nil && resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config of %s: %w", name, err)
+	}
+	return configDependencies(name, config), nil
+}
+
+// names returns the node names in sorted order.
+func (g *JobGraph) names() []string {
+	names := make([]string, 0, len(g.Nodes))
+	for name := range g.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// successors returns the adjacency list with sorted targets.
+func (g *JobGraph) successors() map[string][]string {
+	adj := make(map[string][]string, len(g.Nodes))
+	for _, e := range g.Edges {
+		adj[e.From] = append(adj[e.From], e.To)
+	}
+	for k := range adj {
+		sort.Strings(adj[k])
+	}
+	return adj
+}
+
+// Downstream returns every job transitively triggered by name, in breadth-first order.
+func (g *JobGraph) Downstream(name string) []string {
+	adj := g.successors()
+	result := make([]string, 0)
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range adj[cur] {
+			if !seen[next] {
+				seen[next] = true
+				result = append(result, next)
+				queue = append(queue, next)
+			}
+		}
+	}
+	return result
+}
+
+// Cycles returns every cycle of the graph as the list of jobs in it,
+// using Tarjan's strongly connected components.
+func (g *JobGraph) Cycles() [][]string {
+	adj := g.successors()
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	cycles := make([][]string, 0)
+	counter := 0
+
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = counter
+		low[v] = counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adj[v] {
+			if _, ok := index[w]; !ok {
+				connect(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		component := make([]string, 0)
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		selfLoop := g.seen[[2]string{v, v}]
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, name := range g.names() {
+		if _, ok := index[name]; !ok {
+			connect(name)
+		}
+	}
+	return cycles
+}
+
+// TopologicalOrder returns the jobs so that every job comes before the jobs it
+// triggers. Ties are broken alphabetically. A cycle yields an *ErrDependencyCycle.
+func (g *JobGraph) TopologicalOrder() ([]string, error) {
+	adj := g.successors()
+	inDegree := make(map[string]int, len(g.Nodes))
+	for _, e := range g.Edges {
+		inDegree[e.To]++
+	}
+	ready := make([]string, 0)
+	for _, name := range g.names() {
+		if inDegree[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	order := make([]string, 0, len(g.Nodes))
+	for len(ready) > 0 {
+		cur := ready[0]
+		ready = ready[1:]
+		order = append(order, cur)
+		for _, next := range adj[cur] {
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				ready = append(ready, next)
+				sort.Strings(ready)
+			}
+		}
+	}
+	if len(order) != len(g.Nodes) {
+		cycle := []string{}
+		if cycles := g.Cycles(); len(cycles) > 0 {
+			cycle = cycles[0]
+		}
+		return order, &ErrDependencyCycle{Cycle: cycle}
+	}
+	return order, nil
+}
+
+// sortedEdges returns the edges ordered by From, then To.
+func (g *JobGraph) sortedEdges() []JobEdge {
+	edges := make([]JobEdge, len(g.Edges))
+	copy(edges, g.Edges)
+	sort.Slice(edges, func(a, b int) bool {
+		if edges[a].From != edges[b].From {
+			return edges[a].From < edges[b].From
+		}
+		return edges[a].To < edges[b].To
+	})
+	return edges
+}
+
+// DOT renders the graph in Graphviz DOT format.
+func (g *JobGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph jobs {\n")
+	for _, name := range g.names() {
+		fmt.Fprintf(&b, "  %q;\n", name)
+	}
+	for _, e := range g.sortedEdges() {
+		if e.Kind == EdgeConfig {
+			fmt.Fprintf(&b, "  %q -> %q [style=dashed];\n", e.From, e.To)
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (g *JobGraph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(g.Nodes))
+	for i, name := range g.names() {
+		ids[name] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[name], strings.ReplaceAll(name, `"`, "#quot;"))
+	}
+	for _, e := range g.sortedEdges() {
+		arrow := "-->"
+		if e.Kind == EdgeConfig {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+	return b.String()
+}
+
+// MarshalJSON renders the graph as {"nodes": [...], "edges": [...]} in a stable order.
+func (g *JobGraph) MarshalJSON() ([]byte, error) {
+	nodes := make([]*JobGraphNode, 0, len(g.Nodes))
+	for _, name := range g.names() {
+		nodes = append(nodes, g.Nodes[name])
+	}
+	return json.Marshal(struct {
+		Nodes []*JobGraphNode `json:"nodes"`
+		Edges []JobEdge       `json:"edges"`
+	}{nodes, g.sortedEdges()})
+}
@@ -0,0 +1,169 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFullNameFromURL(t *testing.T) {
+	assert.Equal(t, "a/b c", fullNameFromURL("http://jenkins.local", "http://jenkins.local/job/a/job/b%20c/"))
+	assert.Equal(t, "x", fullNameFromURL("http://jenkins.local/ci", "http://jenkins.local/ci/job/x/"))
+}
+
+func TestConfigDependencies(t *testing.T) {
+	config := `<project><projects>deploy, ../shared/notify</projects>
+<script>build job: &apos;child&apos;, wait: false
+build(&apos;/top&apos;)
+build job: &quot;${NEXT}&quot;</script></project>`
+	deps := configDependencies("team/api/build", config)
+	assert.Equal(t, []string{"team/api/deploy", "team/shared/notify", "team/api/child", "top"}, deps)
+}
+
+func TestDependencyGraph(t *testing.T) {
+	jenkins := newMockJenkins()
+	jenkins.Server = "http://jenkins.local"
+	jobs := map[string]graphJobResponse{
+		"/job/base": {DownstreamProjects: []InnerJob{{Name: "app", Url: "http://jenkins.local/job/app/"}}},
+		"/job/app": {
+			UpstreamProjects:   []InnerJob{{Name: "base", Url: "http://jenkins.local/job/base/"}},
+			DownstreamProjects: []InnerJob{{Name: "deploy", Url: "http://jenkins.local/job/deploy/"}},
+		},
+		"/job/deploy": {UpstreamProjects: []InnerJob{{Name: "app", Url: "http://jenkins.local/job/app/"}}},
+	}
+	jenkins.Requester.(*MockRequester).GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		*response.(*graphJobResponse) = jobs[endpoint]
+		return &http.Response{StatusCode: 200}, nil
+	}
+
+	g, err := jenkins.DependencyGraph(context.Background(), "app")
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(g.Nodes))
+	assert.Equal(t, 2, len(g.Edges))
+	assert.Equal(t, []string{"app", "deploy"}, g.Downstream("base"))
+
+	order, err := g.TopologicalOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base", "app", "deploy"}, order)
+	assert.Empty(t, g.Cycles())
+
+	assert.Contains(t, g.DOT(), `"base" -> "app";`)
+	assert.Contains(t, g.Mermaid(), "n1 --> n0")
+
+	data, err := json.Marshal(g)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `{"from":"app","to":"deploy","kind":"trigger"}`)
+}
+
+func TestDependencyGraph_Diamond(t *testing.T) {
+	jenkins := newMockJenkins()
+	jenkins.Server = "http://jenkins.local"
+	link := func(names ...string) []InnerJob {
+		jobs := make([]InnerJob, len(names))
+		for i, name := range names {
+			jobs[i] = InnerJob{Name: name, Url: "http://jenkins.local/job/" + name + "/"}
+		}
+		return jobs
+	}
+	// base triggers lib-a and lib-b, which both trigger app, and also other
+	jobs := map[string]graphJobResponse{
+		"/job/base":  {DownstreamProjects: link("lib-a", "lib-b", "other")},
+		"/job/lib-a": {UpstreamProjects: link("base"), DownstreamProjects: link("app")},
+		"/job/lib-b": {UpstreamProjects: link("base"), DownstreamProjects: link("app")},
+		"/job/app":   {UpstreamProjects: link("lib-a", "lib-b")},
+		"/job/other": {UpstreamProjects: link("base")},
+	}
+	fetched := make(map[string]int)
+	jenkins.Requester.(*MockRequester).GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		fetched[endpoint]++
+		*response.(*graphJobResponse) = jobs[endpoint]
+		return &http.Response{StatusCode: 200}, nil
+	}
+
+	g, err := jenkins.DependencyGraph(context.Background(), "app")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "base", "lib-a", "lib-b"}, g.names())
+	assert.Equal(t, 4, len(g.Edges))
+
+	g, err = jenkins.DependencyGraph(context.Background(), "lib-a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "base", "lib-a"}, g.names())
+	assert.Equal(t, []string{"lib-a", "app"}, g.Downstream("base"))
+	assert.Equal(t, 2, fetched["/job/lib-a"])
+}
+
+func TestDependencyGraph_DeletedJob(t *testing.T) {
+	jenkins := newMockJenkins()
+	jenkins.Server = "http://jenkins.local"
+	jobs := map[string]graphJobResponse{
+		"/job/app": {DownstreamProjects: []InnerJob{
+			{Name: "gone", Url: "http://jenkins.local/job/gone/"},
+			{Name: "deploy", Url: "http://jenkins.local/job/deploy/"},
+		}},
+		"/job/deploy": {},
+	}
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		job, ok := jobs[endpoint]
+		if !ok {
+			return &http.Response{StatusCode: 404}, assert.AnError
+		}
+		*response.(*graphJobResponse) = job
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.GetXMLFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		if endpoint != "/job/app/config.xml" {
+			return &http.Response{StatusCode: 403}, assert.AnError
+		}
+		*response.(*string) = "<project><projects>removed</projects></project>"
+		return &http.Response{StatusCode: 200}, nil
+	}
+
+	g, err := jenkins.BuildDependencyGraph(context.Background(), &DependencyGraphOptions{Direction: GraphDownstream, ScanConfig: true}, "app")
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(g.Nodes))
+	assert.True(t, g.Nodes["gone"].Unresolved)
+	assert.True(t, g.Nodes["removed"].Unresolved)
+	assert.False(t, g.Nodes["deploy"].Unresolved)
+	assert.Equal(t, []string{"deploy", "gone", "removed"}, g.Downstream("app"))
+
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		return nil, assert.AnError
+	}
+	_, err = jenkins.DependencyGraph(context.Background(), "app")
+	assert.Error(t, err)
+}
+
+func TestJobGraph_Cycles(t *testing.T) {
+	g := NewJobGraph()
+	g.AddEdge("a", "b", EdgeTrigger)
+	g.AddEdge("b", "c", EdgeTrigger)
+	g.AddEdge("c", "a", EdgeConfig)
+	g.AddEdge("c", "d", EdgeTrigger)
+	g.AddEdge("e", "e", EdgeTrigger)
+
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"e"}}, g.Cycles())
+
+	_, err := g.TopologicalOrder()
+	var cycleErr *ErrDependencyCycle
+	require.ErrorAs(t, err, &cycleErr)
+	assert.Equal(t, []string{"a", "b", "c"}, cycleErr.Cycle)
+}
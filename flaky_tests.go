@@ -0,0 +1,213 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// number of test reports fetched at once by FlakyTests
+const flakyTestsConcurrency = 4
+
+// FlakyTest is a test whose outcome changed between builds without any
+// commit in between. Score is the share of consecutive runs in which it
+// flipped, from 0 to 1.
+type FlakyTest struct {
+	ClassName    string  `json:"className"`
+	Name         string  `json:"name"`
+	Score        float64 `json:"score"`
+	Flips        int     `json:"flips"`
+	Runs         int     `json:"runs"`
+	FailedBuilds []int64 `json:"failedBuilds"`
+}
+
+// FlakyTests is a list of flaky tests, sorted by descending score by default.
+type FlakyTests []FlakyTest
+
+func (f FlakyTests) Len() int      { return len(f) }
+func (f FlakyTests) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f FlakyTests) Less(i, j int) bool {
+	if f[i].Score != f[j].Score {
+		return f[i].Score > f[j].Score
+	}
+	if f[i].Flips != f[j].Flips {
+		return f[i].Flips > f[j].Flips
+	}
+	return f[i].ClassName+"."+f[i].Name < f[j].ClassName+"."+f[j].Name
+}
+
+// WriteJSON writes the list as a JSON array.
+func (f FlakyTests) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(f)
+}
+
+// WriteCSV writes the list as CSV with a header row. Failed builds are
+// separated by spaces.
+func (f FlakyTests) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"className", "name", "score", "flips", "runs", "failedBuilds"}); err != nil {
+		return err
+	}
+	for _, t := range f {
+		builds := make([]string, len(t.FailedBuilds))
+		for i, b := range t.FailedBuilds {
+			builds[i] = strconv.FormatInt(b, 10)
+		}
+		record := []string{t.ClassName, t.Name, strconv.FormatFloat(t.Score, 'f', 3, 64), strconv.Itoa(t.Flips), strconv.Itoa(t.Runs), strings.Join(builds, " ")}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type flakyBuild struct {
+	Number     int64            `json:"number"`
+	ChangeSet  BuildChangeSet   `json:"changeSet"`
+	ChangeSets []BuildChangeSet `json:"changeSets"`
+}
+
+func (b *flakyBuild) hasChanges() bool {
+	if len(b.ChangeSet.Items) > 0 {
+		return true
+	}
+	for _, cs := range b.ChangeSets {
+		if len(cs.Items) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// FlakyTests analyzes the test reports of the last window builds and returns
+// the tests whose outcome flipped between two builds without any commit
+// in the later one. Builds without a test report are ignored.
+func (j *Job) FlakyTests(ctx context.Context, window int) (FlakyTests, error) {
+	if window < 1 {
+		return nil, fmt.Errorf("number of builds must be at least 1, got %d", window)
+	}
+	var resp struct {
+		Builds []flakyBuild `json:"allBuilds"`
+	}
+	tree := fmt.Sprintf("allBuilds[number,changeSet[items[commitId]],changeSets[items[commitId]]]{0,%d}", window)
+	if _, err := j.Jenkins.Requester.GetJSON(ctx, j.Base, &resp, map[string]string{"tree": tree}); err != nil {
+		return nil, err
+	}
+	builds := resp.Builds
+	sort.Slice(builds, func(a, b int) bool { return builds[a].Number < builds[b].Number })
+
+	reports, err := j.testReports(ctx, builds)
+	if err != nil {
+		return nil, err
+	}
+	return findFlakyTests(builds, reports), nil
+}
+
+// testReports fetches the test reports of the builds concurrently; the
+// report of a build without tests is nil.
+func (j *Job) testReports(ctx context.Context, builds []flakyBuild) ([]*TestResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	reports := make([]*TestResult, len(builds))
+	errs := make([]error, len(builds))
+	sem := make(chan struct{}, flakyTestsConcurrency)
+	var wg sync.WaitGroup
+	for i := range builds {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			build := &Build{Jenkins: j.Jenkins, Job: j, Raw: &BuildResponse{Number: builds[i].Number}, Depth: 1, Base: j.Base + "/" + strconv.FormatInt(builds[i].Number, 10)}
+			report, err := build.testReport(ctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("test report of build %d: %w", builds[i].Number, err)
+				cancel()
+				return
+			}
+			reports[i] = report
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reports, nil
+}
+
+// findFlakyTests walks the outcomes of every test from the oldest build on.
+func findFlakyTests(builds []flakyBuild, reports []*TestResult) FlakyTests {
+	type history struct {
+		flaky    FlakyTest
+		failed   bool
+		lastSeen int
+	}
+	tests := make(map[string]*history)
+	for i, report := range reports {
+		if report == nil {
+			continue
+		}
+		for _, c := range report.Cases() {
+			if c.IsSkipped() {
+				continue
+			}
+			h, ok := tests[c.FullName()]
+			if !ok {
+				h = &history{flaky: FlakyTest{ClassName: c.ClassName, Name: c.Name, FailedBuilds: make([]int64, 0)}}
+				tests[c.FullName()] = h
+			} else if h.failed != c.IsFailed() && !changedBetween(builds, h.lastSeen, i) {
+				h.flaky.Flips++
+			}
+			h.flaky.Runs++
+			h.failed = c.IsFailed()
+			h.lastSeen = i
+			if h.failed {
+				h.flaky.FailedBuilds = append(h.flaky.FailedBuilds, builds[i].Number)
+			}
+		}
+	}
+
+	flaky := make(FlakyTests, 0)
+	for _, h := range tests {
+		if h.flaky.Flips == 0 {
+			continue
+		}
+		h.flaky.Score = float64(h.flaky.Flips) / float64(h.flaky.Runs-1)
+		flaky = append(flaky, h.flaky)
+	}
+	sort.Sort(flaky)
+	return flaky
+}
+
+// changedBetween reports whether any build after from up to to has commits.
+func changedBetween(builds []flakyBuild, from, to int) bool {
+	for i := from + 1; i <= to; i++ {
+		if builds[i].hasChanges() {
+			return true
+		}
+	}
+	return false
+}
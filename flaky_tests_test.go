@@ -0,0 +1,100 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func flakyReport(login, logout string) string {
+	return `{"suites": [{"name": "app.LoginTest", "cases": [
+		{"className": "app.LoginTest", "name": "testLogin", "status": "` + login + `"},
+		{"className": "app.LoginTest", "name": "testLogout", "status": "` + logout + `"}
+	]}]}`
+}
+
+func TestJob_FlakyTests(t *testing.T) {
+	endpoints := map[string]string{
+		"/job/app": `{"allBuilds": [
+			{"number": 5}, {"number": 4}, {"number": 3, "changeSets": [{"items": [{"commitId": "c3"}]}]}, {"number": 2}, {"number": 1}
+		]}`,
+		// testLogin flips without commits, testLogout only breaks with the commit of build 3
+		"/job/app/1/testReport": flakyReport("PASSED", "PASSED"),
+		"/job/app/2/testReport": flakyReport("FAILED", "PASSED"),
+		"/job/app/3/testReport": flakyReport("PASSED", "FAILED"),
+		"/job/app/5/testReport": flakyReport("REGRESSION", "FAILED"),
+	}
+	jenkins := newMockJenkins()
+	var mu sync.Mutex
+	jenkins.Requester.(*MockRequester).GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if endpoint == "/job/app" {
+			assert.Contains(t, query["tree"], "{0,5}")
+		}
+		data, ok := endpoints[endpoint]
+		if !ok {
+			return &http.Response{StatusCode: 404}, assert.AnError
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), response))
+		return &http.Response{StatusCode: 200}, nil
+	}
+	job := &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/app"}
+
+	flaky, err := job.FlakyTests(context.Background(), 5)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(flaky))
+	assert.Equal(t, FlakyTest{
+		ClassName:    "app.LoginTest",
+		Name:         "testLogin",
+		Score:        2.0 / 3.0,
+		Flips:        2,
+		Runs:         4,
+		FailedBuilds: []int64{2, 5},
+	}, flaky[0])
+}
+
+func TestJob_FlakyTests_InvalidWindow(t *testing.T) {
+	job := &Job{Jenkins: newMockJenkins(), Raw: &JobResponse{}, Base: "/job/app"}
+	for _, window := range []int{0, -3} {
+		_, err := job.FlakyTests(context.Background(), window)
+		assert.EqualError(t, err, fmt.Sprintf("number of builds must be at least 1, got %d", window))
+	}
+}
+
+func TestFlakyTests_Export(t *testing.T) {
+	flaky := FlakyTests{
+		{ClassName: "a.B", Name: "low", Score: 0.25, Flips: 1, Runs: 5, FailedBuilds: []int64{3}},
+		{ClassName: "a.B", Name: "high", Score: 0.5, Flips: 2, Runs: 5, FailedBuilds: []int64{2, 4}},
+	}
+	assert.True(t, flaky.Less(1, 0))
+
+	var buf bytes.Buffer
+	require.NoError(t, flaky.WriteCSV(&buf))
+	assert.Equal(t, "className,name,score,flips,runs,failedBuilds\na.B,low,0.250,1,5,3\na.B,high,0.500,2,5,2 4\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, flaky.WriteJSON(&buf))
+	assert.Contains(t, buf.String(), `"failedBuilds":[2,4]`)
+}
@@ -73,6 +73,29 @@ func (f *Folder) Create(ctx context.Context, name string) (*Folder, error) {
 	return nil, errors.New(strconv.Itoa(r.StatusCode))
 }
 
+// GetConfig retrieves the folder's XML configuration.
+func (f *Folder) GetConfig(ctx context.Context) (string, error) {
+	var data string
+	_, err := f.Jenkins.Requester.GetXML(ctx, f.Base+"/config.xml", &data, nil)
+	if err != nil {
+		return "", err
+	}
+	return data, nil
+}
+
+// UpdateConfig updates the folder's XML configuration.
+func (f *Folder) UpdateConfig(ctx context.Context, config string) error {
+	resp, err := f.Jenkins.Requester.PostXML(ctx, f.Base+"/config.xml", config, nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == 200 {
+		_, _ = f.Poll(ctx)
+		return nil
+	}
+	return errors.New(strconv.Itoa(resp.StatusCode))
+}
+
 // Poll fetches the latest folder data from Jenkins.
 func (f *Folder) Poll(ctx context.Context) (int, error) {
 	response, err := f.Jenkins.Requester.GetJSON(ctx, f.Base, f.Raw, nil)
@@ -0,0 +1,195 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// this file implements the pipeline-model-definition converter API:
+// https://github.com/jenkinsci/pipeline-model-definition-plugin/blob/master/EXTENDING.md
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	converterURL          = "/pipeline-model-converter"
+	validateJenkinsfileOK = "successfully validated"
+)
+
+// matches "WorkflowScript: 3: Expected a stage @ line 3, column 9."
+var diagnosticRegex = regexp.MustCompile(`^(?:WorkflowScript: \d+: )?(.+?) @ line (\d+), column (\d+)\.$`)
+
+// JenkinsfileDiagnostic is a problem found in a Jenkinsfile.
+// Line and Column are 1-based and 0 when the error has no position.
+type JenkinsfileDiagnostic struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic as "line:column: message", the way compilers
+// and most editors expect it.
+func (d JenkinsfileDiagnostic) String() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// ErrInvalidJenkinsfile occurs when the converter rejects a Jenkinsfile.
+type ErrInvalidJenkinsfile struct {
+	Diagnostics []JenkinsfileDiagnostic
+}
+
+func (e *ErrInvalidJenkinsfile) Error() string {
+	msgs := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		msgs[i] = d.String()
+	}
+	return "invalid Jenkinsfile: " + strings.Join(msgs, "; ")
+}
+
+// converterResponse is the envelope of the converter JSON endpoints.
+type converterResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Result      string          `json:"result"`
+		JSON        json.RawMessage `json:"json"`
+		Jenkinsfile string          `json:"jenkinsfile"`
+		Errors      []struct {
+			Error interface{} `json:"error"`
+		} `json:"errors"`
+	} `json:"data"`
+}
+
+// ValidateJenkinsfile lints a declarative Jenkinsfile on the server.
+// It returns no diagnostics when the Jenkinsfile is valid.
+func (j *Jenkins) ValidateJenkinsfile(ctx context.Context, script string) ([]JenkinsfileDiagnostic, error) {
+	var out string
+	data := url.Values{"jenkinsfile": {script}}
+	resp, err := j.Requester.Post(ctx, converterURL+"/validate", bytes.NewBufferString(data.Encode()), &out, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("validating Jenkinsfile: status %d", resp.StatusCode)
+	}
+	return parseValidateOutput(out), nil
+}
+
+// parseValidateOutput turns the plain text report of the validate endpoint
+// into diagnostics, skipping the source excerpts under each error.
+func parseValidateOutput(out string) []JenkinsfileDiagnostic {
+	diagnostics := make([]JenkinsfileDiagnostic, 0)
+	if strings.Contains(out, validateJenkinsfileOK) {
+		return diagnostics
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if d, ok := parseDiagnostic(strings.TrimSpace(line)); ok {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	if len(diagnostics) == 0 && strings.TrimSpace(out) != "" {
+		diagnostics = append(diagnostics, JenkinsfileDiagnostic{Message: strings.TrimSpace(out)})
+	}
+	return diagnostics
+}
+
+func parseDiagnostic(line string) (JenkinsfileDiagnostic, bool) {
+	m := diagnosticRegex.FindStringSubmatch(line)
+	if m == nil {
+		return JenkinsfileDiagnostic{}, false
+	}
+	d := JenkinsfileDiagnostic{Message: m[1]}
+	d.Line, _ = strconv.Atoi(m[2])
+	d.Column, _ = strconv.Atoi(m[3])
+	return d, true
+}
+
+// convert posts a single form field to a converter endpoint and returns the
+// decoded envelope, or *ErrInvalidJenkinsfile when the conversion failed.
+func (j *Jenkins) convert(ctx context.Context, endpoint string, field string, value string) (*converterResponse, error) {
+	resp := new(converterResponse)
+	data := url.Values{field: {value}}
+	r, err := j.Requester.Post(ctx, converterURL+"/"+endpoint, bytes.NewBufferString(data.Encode()), resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status %d", endpoint, r.StatusCode)
+	}
+	if resp.Data.Result != "success" {
+		e := &ErrInvalidJenkinsfile{}
+		for _, item := range resp.Data.Errors {
+			e.Diagnostics = append(e.Diagnostics, converterDiagnostics(item.Error)...)
+		}
+		return nil, e
+	}
+	return resp, nil
+}
+
+// converterDiagnostics flattens an error entry, which is either a message or
+// a list of messages.
+func converterDiagnostics(v interface{}) []JenkinsfileDiagnostic {
+	switch e := v.(type) {
+	case string:
+		if d, ok := parseDiagnostic(e); ok {
+			return []JenkinsfileDiagnostic{d}
+		}
+		return []JenkinsfileDiagnostic{{Message: e}}
+	case []interface{}:
+		diagnostics := make([]JenkinsfileDiagnostic, 0, len(e))
+		for _, item := range e {
+			diagnostics = append(diagnostics, converterDiagnostics(item)...)
+		}
+		return diagnostics
+	default:
+		return []JenkinsfileDiagnostic{{Message: fmt.Sprint(v)}}
+	}
+}
+
+// JenkinsfileToJSON converts a declarative Jenkinsfile to its JSON representation.
+func (j *Jenkins) JenkinsfileToJSON(ctx context.Context, script string) (json.RawMessage, error) {
+	resp, err := j.convert(ctx, "toJson", "jenkinsfile", script)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.JSON, nil
+}
+
+// JSONToJenkinsfile converts the JSON representation of a declarative pipeline
+// back to a Jenkinsfile.
+func (j *Jenkins) JSONToJenkinsfile(ctx context.Context, pipeline json.RawMessage) (string, error) {
+	resp, err := j.convert(ctx, "toJenkinsfile", "json", string(pipeline))
+	if err != nil {
+		return "", err
+	}
+	return resp.Data.Jenkinsfile, nil
+}
+
+// StepsToJSON converts a snippet of pipeline steps to their JSON representation.
+func (j *Jenkins) StepsToJSON(ctx context.Context, steps string) (json.RawMessage, error) {
+	resp, err := j.convert(ctx, "stepsToJson", "jenkinsfile", steps)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.JSON, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validateFailure = `Errors encountered validating Jenkinsfile:
+WorkflowScript: 3: Expected a stage @ line 3, column 9.
+           stages {
+           ^
+
+WorkflowScript: 1: Missing required section "agent" @ line 1, column 1.
+   pipeline {
+   ^
+
+2 errors
+`
+
+func newConverterMock(t *testing.T, endpoint string, field string, body string) *Jenkins {
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).PostFunc = func(ctx context.Context, e string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+		assert.Equal(t, endpoint, e)
+		data, _ := io.ReadAll(payload)
+		form, _ := url.ParseQuery(string(data))
+		assert.NotEmpty(t, form.Get(field))
+		switch r := response.(type) {
+		case *string:
+			*r = body
+		default:
+			require.NoError(t, json.Unmarshal([]byte(body), r))
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	return jenkins
+}
+
+// newValidateServer returns a client of a server answering the validate
+// endpoint with body as plain text, like Jenkins does.
+func newValidateServer(t *testing.T, body string) *Jenkins {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pipeline-model-converter/validate" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, r.ParseForm())
+		assert.NotEmpty(t, r.PostForm.Get("jenkinsfile"))
+		w.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return CreateJenkins(server.Client(), server.URL)
+}
+
+func TestValidateJenkinsfile(t *testing.T) {
+	jenkins := newValidateServer(t, validateFailure)
+
+	diagnostics, err := jenkins.ValidateJenkinsfile(context.Background(), "pipeline {\n  stages {\n}")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(diagnostics))
+	assert.Equal(t, JenkinsfileDiagnostic{Line: 3, Column: 9, Message: "Expected a stage"}, diagnostics[0])
+	assert.Equal(t, `1:1: Missing required section "agent"`, diagnostics[1].String())
+}
+
+func TestValidateJenkinsfile_Valid(t *testing.T) {
+	jenkins := newValidateServer(t, "Jenkinsfile successfully validated.\n")
+
+	diagnostics, err := jenkins.ValidateJenkinsfile(context.Background(), "pipeline {}")
+	require.NoError(t, err)
+	assert.Empty(t, diagnostics)
+}
+
+func TestJenkinsfileToJSON(t *testing.T) {
+	jenkins := newConverterMock(t, "/pipeline-model-converter/toJson", "jenkinsfile",
+		`{"status":"ok","data":{"result":"success","json":{"pipeline":{"stages":[]}}}}`)
+
+	out, err := jenkins.JenkinsfileToJSON(context.Background(), "pipeline {}")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"pipeline":{"stages":[]}}`, string(out))
+}
+
+func TestJenkinsfileToJSON_Failure(t *testing.T) {
+	jenkins := newConverterMock(t, "/pipeline-model-converter/toJson", "jenkinsfile",
+		`{"status":"ok","data":{"result":"failure","errors":[{"error":["Expected a stage @ line 3, column 9.","Missing agent"]}]}}`)
+
+	_, err := jenkins.JenkinsfileToJSON(context.Background(), "pipeline {}")
+	var invalid *ErrInvalidJenkinsfile
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []JenkinsfileDiagnostic{{Line: 3, Column: 9, Message: "Expected a stage"}, {Message: "Missing agent"}}, invalid.Diagnostics)
+}
+
+func TestJSONToJenkinsfile(t *testing.T) {
+	jenkins := newConverterMock(t, "/pipeline-model-converter/toJenkinsfile", "json",
+		`{"status":"ok","data":{"result":"success","jenkinsfile":"pipeline {\n}"}}`)
+
+	out, err := jenkins.JSONToJenkinsfile(context.Background(), json.RawMessage(`{"pipeline":{}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "pipeline {\n}", out)
+}
+
+func TestStepsToJSON(t *testing.T) {
+	jenkins := newConverterMock(t, "/pipeline-model-converter/stepsToJson", "jenkinsfile",
+		`{"status":"ok","data":{"result":"success","json":[{"name":"echo"}]}}`)
+
+	out, err := jenkins.StepsToJSON(context.Background(), "echo 'hi'")
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"echo"}]`, string(out))
+}
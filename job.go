@@ -584,42 +584,3 @@ func (j *Job) History(ctx context.Context) ([]*History, error) {
 
 	return parseBuildHistory(strings.NewReader(s)), nil
 }
-
-// ProceedInput submits the first pending input action for a pipeline run.
-func (pr *PipelineRun) ProceedInput(ctx context.Context) (bool, error) {
-	actions, _ := pr.GetPendingInputActions(ctx)
-	data := url.Values{}
-	data.Set("inputId", actions[0].ID)
-	params := make(map[string]string)
-	data.Set("json", makeJson(params))
-
-	href := pr.Base + "/wfapi/inputSubmit"
-
-	resp, err := pr.Job.Jenkins.Requester.Post(ctx, href, bytes.NewBufferString(data.Encode()), nil, nil)
-	if err != nil {
-		return false, err
-	}
-	if resp.StatusCode != 200 {
-		return false, errors.New(strconv.Itoa(resp.StatusCode))
-	}
-	return true, nil
-}
-
-// AbortInput aborts the first pending input action for a pipeline run.
-func (pr *PipelineRun) AbortInput(ctx context.Context) (bool, error) {
-	actions, _ := pr.GetPendingInputActions(ctx)
-	data := url.Values{}
-	params := make(map[string]string)
-	data.Set("json", makeJson(params))
-
-	href := pr.Base + "/input/" + actions[0].ID + "/abort"
-
-	resp, err := pr.Job.Jenkins.Requester.Post(ctx, href, bytes.NewBufferString(data.Encode()), nil, nil)
-	if err != nil {
-		return false, err
-	}
-	if resp.StatusCode != 200 {
-		return false, errors.New(strconv.Itoa(resp.StatusCode))
-	}
-	return true, nil
-}
@@ -0,0 +1,223 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaults of LogSearchOptions
+const (
+	logSearchLastBuilds  = 10
+	logSearchConcurrency = 4
+)
+
+// LogSearchOptions selects the builds searched by Job.SearchLogs.
+// Builds started in [Since, Until) are searched when either is set,
+// otherwise the LastBuilds most recent builds (10 by default).
+type LogSearchOptions struct {
+	LastBuilds   int
+	Since        time.Time
+	Until        time.Time
+	Concurrency  int // number of logs read at once, 4 by default
+	ContextLines int // lines kept before and after each match
+}
+
+// LogMatch is a console line matching the search pattern.
+// Line is 1-based.
+type LogMatch struct {
+	Build  int64    `json:"build"`
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+// LogSearchResult holds the matches of a search, ordered by build and line,
+// and the numbers of the builds that were searched, oldest first.
+type LogSearchResult struct {
+	Matches  []LogMatch
+	Searched []int64
+}
+
+// FirstBuild returns the oldest build with a match.
+func (r *LogSearchResult) FirstBuild() (int64, bool) {
+	if len(r.Matches) == 0 {
+		return 0, false
+	}
+	return r.Matches[0].Build, true
+}
+
+type searchBuild struct {
+	Number    int64 `json:"number"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// SearchLogs scans the console output of a range of builds for lines
+// matching pattern. Logs are streamed line by line and never held in memory.
+func (j *Job) SearchLogs(ctx context.Context, pattern *regexp.Regexp, opts *LogSearchOptions) (*LogSearchResult, error) {
+	if opts == nil {
+		opts = &LogSearchOptions{}
+	}
+	numbers, err := j.searchBuilds(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = logSearchConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	matches := make([][]LogMatch, len(numbers))
+	errs := make([]error, len(numbers))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, number := range numbers {
+		wg.Add(1)
+		go func(i int, number int64) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			build := &Build{Jenkins: j.Jenkins, Job: j, Raw: &BuildResponse{Number: number}, Depth: 1, Base: j.Base + "/" + strconv.FormatInt(number, 10)}
+			matches[i], errs[i] = build.searchLog(ctx, pattern, opts.ContextLines)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, number)
+	}
+	wg.Wait()
+
+	result := &LogSearchResult{Matches: make([]LogMatch, 0), Searched: numbers}
+	for i := range numbers {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("searching log of build %d: %w", numbers[i], errs[i])
+		}
+		result.Matches = append(result.Matches, matches[i]...)
+	}
+	return result, nil
+}
+
+// searchBuilds returns the numbers of the builds selected by opts, oldest first.
+func (j *Job) searchBuilds(ctx context.Context, opts *LogSearchOptions) ([]int64, error) {
+	window := !opts.Since.IsZero() || !opts.Until.IsZero()
+	n := opts.LastBuilds
+	if n <= 0 {
+		n = logSearchLastBuilds
+	}
+	tree := "allBuilds[number,timestamp]"
+	if !window {
+		// allBuilds lists the newest builds first
+		tree += fmt.Sprintf("{0,%d}", n)
+	}
+	var resp struct {
+		Builds []searchBuild `json:"allBuilds"`
+	}
+	_, err := j.Jenkins.Requester.GetJSON(ctx, j.Base, &resp, map[string]string{"tree": tree})
+	if err != nil {
+		return nil, err
+	}
+	builds := resp.Builds
+	sort.Slice(builds, func(a, b int) bool { return builds[a].Number > builds[b].Number })
+
+	numbers := make([]int64, 0)
+	if !window {
+		for _, b := range builds[:min(n, len(builds))] {
+			numbers = append(numbers, b.Number)
+		}
+	} else {
+		for _, b := range builds {
+			started := time.UnixMilli(b.Timestamp)
+			if (opts.Since.IsZero() || !started.Before(opts.Since)) && (opts.Until.IsZero() || started.Before(opts.Until)) {
+				numbers = append(numbers, b.Number)
+			}
+		}
+	}
+	sort.Slice(numbers, func(a, b int) bool { return numbers[a] < numbers[b] })
+	return numbers, nil
+}
+
+// searchLog streams the console output of the build through pattern.
+func (b *Build) searchLog(ctx context.Context, pattern *regexp.Regexp, contextLines int) ([]LogMatch, error) {
+	s := &logScanner{build: b.GetBuildNumber(), pattern: pattern, context: contextLines}
+	lw := NewLineWriter(s.add)
+	resp, err := b.Jenkins.Requester.Get(ctx, b.Base+"/consoleText", lw, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if err := lw.Flush(); err != nil {
+		return nil, err
+	}
+	return s.matches, nil
+}
+
+// logScanner collects matches and their context lines from a stream of lines.
+type logScanner struct {
+	build   int64
+	pattern *regexp.Regexp
+	context int
+	line    int
+	before  []string
+	// matches still waiting for lines after them
+	pending []int
+	matches []LogMatch
+}
+
+func (s *logScanner) add(line string) error {
+	s.line++
+	open := s.pending[:0]
+	for _, i := range s.pending {
+		s.matches[i].After = append(s.matches[i].After, line)
+		if len(s.matches[i].After) < s.context {
+			open = append(open, i)
+		}
+	}
+	s.pending = open
+
+	if s.pattern.MatchString(line) {
+		m := LogMatch{Build: s.build, Line: s.line, Text: line}
+		if len(s.before) > 0 {
+			m.Before = append([]string(nil), s.before...)
+		}
+		s.matches = append(s.matches, m)
+		if s.context > 0 {
+			s.pending = append(s.pending, len(s.matches)-1)
+		}
+	}
+
+	if s.context > 0 {
+		if len(s.before) == s.context {
+			s.before = s.before[1:]
+		}
+		s.before = append(s.before, line)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLogSearchMock serves builds 1 to 4 and the given logs. The tree asked
+// for the builds must be tree.
+func newLogSearchMock(t *testing.T, tree string, logs map[string]string) *Job {
+	jenkins := newMockJenkins()
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		assert.Equal(t, tree, query["tree"])
+		data := `{"allBuilds":[
+			{"number":4,"timestamp":1704164400000},
+			{"number":3,"timestamp":1704160800000},
+			{"number":2,"timestamp":1704157200000},
+			{"number":1,"timestamp":1704153600000}]}`
+		require.NoError(t, json.Unmarshal([]byte(data), response))
+		return &http.Response{StatusCode: 200}, nil
+	}
+	var mu sync.Mutex
+	mock.GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		log, ok := logs[endpoint]
+		if !ok {
+			return &http.Response{StatusCode: 404}, nil
+		}
+		_, err := io.WriteString(response.(io.Writer), log)
+		return &http.Response{StatusCode: 200}, err
+	}
+	return &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/app"}
+}
+
+func TestJob_SearchLogs(t *testing.T) {
+	job := newLogSearchMock(t, "allBuilds[number,timestamp]{0,3}", map[string]string{
+		"/job/app/2/consoleText": "start\nok\nFinished: SUCCESS\n",
+		"/job/app/3/consoleText": "start\nstep\nERROR: disk full\ncleanup\nFinished: FAILURE",
+		"/job/app/4/consoleText": "ERROR: disk full\nFinished: FAILURE\n",
+	})
+
+	result, err := job.SearchLogs(context.Background(), regexp.MustCompile(`ERROR`), &LogSearchOptions{LastBuilds: 3, Concurrency: 2, ContextLines: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 4}, result.Searched)
+	require.Equal(t, 2, len(result.Matches))
+	assert.Equal(t, LogMatch{Build: 3, Line: 3, Text: "ERROR: disk full", Before: []string{"step"}, After: []string{"cleanup"}}, result.Matches[0])
+	assert.Equal(t, int64(4), result.Matches[1].Build)
+	assert.Nil(t, result.Matches[1].Before)
+
+	first, ok := result.FirstBuild()
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), first)
+}
+
+func TestJob_SearchLogs_TimeWindow(t *testing.T) {
+	job := newLogSearchMock(t, "allBuilds[number,timestamp]", map[string]string{
+		"/job/app/2/consoleText": "nothing",
+		"/job/app/3/consoleText": "nothing",
+	})
+
+	opts := &LogSearchOptions{Since: time.UnixMilli(1704157200000), Until: time.UnixMilli(1704164400000)}
+	result, err := job.SearchLogs(context.Background(), regexp.MustCompile(`ERROR`), opts)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, result.Searched)
+	_, ok := result.FirstBuild()
+	assert.False(t, ok)
+}
+
+func TestJob_SearchLogs_Error(t *testing.T) {
+	job := newLogSearchMock(t, "allBuilds[number,timestamp]{0,2}", map[string]string{"/job/app/4/consoleText": "ok"})
+
+	_, err := job.SearchLogs(context.Background(), regexp.MustCompile(`ERROR`), &LogSearchOptions{LastBuilds: 2})
+	assert.ErrorContains(t, err, "build 3")
+}
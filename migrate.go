@@ -0,0 +1,300 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// quoted values inside config.xml, pipeline scripts are stored XML-escaped
+const configQuote = `(?:'|"|&apos;|&quot;)`
+
+var (
+	pluginAttrRegex     = regexp.MustCompile(`plugin="([^"@]+)@[^"]*"`)
+	credentialsXMLRegex = regexp.MustCompile(`(<credentialsId>)([^<]*)(</credentialsId>)`)
+	credentialsDSLRegex = regexp.MustCompile(`(\b(?:credentialsId\s*:\s*|credentials\s*\(\s*)` + configQuote + `)(.*?)(` + configQuote + `)`)
+	labelXMLRegex       = regexp.MustCompile(`(<(?:assignedNode|label)>)([^<]*)(</(?:assignedNode|label)>)`)
+	labelDSLRegex       = regexp.MustCompile(`(\b(?:label\s*:?\s*|node\s*\(\s*)` + configQuote + `)(.*?)(` + configQuote + `)`)
+	labelOperatorRegex  = regexp.MustCompile(`&&|\|\||<->|->|!|\(|\)|\s+`)
+)
+
+// MigrateOptions controls how Migrate copies a folder subtree between controllers.
+type MigrateOptions struct {
+	// Root is the folder to copy from the source controller, empty for everything.
+	Root string
+	// ImportOptions decides where and how items are created on the destination.
+	ImportOptions
+	// URLs maps URL prefixes found in configs to their replacement. The source
+	// server URL is always mapped to the destination server URL.
+	URLs map[string]string
+	// Credentials maps credential IDs on the source to IDs on the destination.
+	Credentials map[string]string
+	// Labels maps node label atoms on the source to labels on the destination.
+	Labels map[string]string
+	// DryRun builds the report without creating anything on the destination.
+	DryRun bool
+}
+
+// MigrationIssue describes an item that was not migrated or needs attention.
+type MigrationIssue struct {
+	Kind     string
+	FullName string
+	Reason   string
+}
+
+// MigrationReport summarizes the outcome of Migrate.
+type MigrationReport struct {
+	Imported       []ImportResult
+	NotMigrated    []MigrationIssue
+	Warnings       []MigrationIssue
+	MissingPlugins []string
+}
+
+// configRewriter applies the translation tables of MigrateOptions to a config.xml.
+type configRewriter struct {
+	urls        []string
+	urlMap      map[string]string
+	credentials map[string]string
+	labels      map[string]string
+}
+
+func newConfigRewriter(src, dst *Jenkins, opts *MigrateOptions) *configRewriter {
+	rw := &configRewriter{
+		urlMap:      make(map[string]string),
+		credentials: opts.Credentials,
+		labels:      opts.Labels,
+	}
+	if src.Server != "" && src.Server != dst.Server {
+		rw.urlMap[src.Server] = dst.Server
+	}
+	for from, to := range opts.URLs {
+		rw.urlMap[from] = to
+	}
+	for from := range rw.urlMap {
+		rw.urls = append(rw.urls, from)
+	}
+	// longest prefix first, so that more specific rewrites win
+	sort.Slice(rw.urls, func(a, b int) bool {
+		if len(rw.urls[a]) != len(rw.urls[b]) {
+			return len(rw.urls[a]) > len(rw.urls[b])
+		}
+		return rw.urls[a] < rw.urls[b]
+	})
+	return rw
+}
+
+func (rw *configRewriter) rewrite(config string) string {
+	for _, from := range rw.urls {
+		config = strings.ReplaceAll(config, from, rw.urlMap[from])
+	}
+	if len(rw.credentials) > 0 {
+		translate := func(id string) string {
+			if to, ok := rw.credentials[id]; ok {
+				return to
+			}
+			return id
+		}
+		config = replaceSubmatch(credentialsXMLRegex, config, translate)
+		config = replaceSubmatch(credentialsDSLRegex, config, translate)
+	}
+	if len(rw.labels) > 0 {
+		translate := func(expr string) string {
+			return translateLabelExpr(expr, rw.labels)
+		}
+		config = replaceSubmatch(labelXMLRegex, config, translate)
+		config = replaceSubmatch(labelDSLRegex, config, translate)
+	}
+	return config
+}
+
+// replaceSubmatch rewrites the second group of every match of a three group regex.
+func replaceSubmatch(re *regexp.Regexp, s string, fn func(string) string) string {
+	return re.ReplaceAllStringFunc(s, func(match string) string {
+		groups := re.FindStringSubmatch(match)
+		return groups[1] + fn(groups[2]) + groups[3]
+	})
+}
+
+// translateLabelExpr maps every label atom of a label expression such as
+// "linux && (docker || podman)" through table, keeping the operators.
+func translateLabelExpr(expr string, table map[string]string) string {
+	var b strings.Builder
+	last := 0
+	atom := func(s string) string {
+		if to, ok := table[s]; ok {
+			return to
+		}
+		return s
+	}
+	for _, loc := range labelOperatorRegex.FindAllStringIndex(expr, -1) {
+		b.WriteString(atom(expr[last:loc[0]]))
+		b.WriteString(expr[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(atom(expr[last:]))
+	return b.String()
+}
+
+// requiredPlugins returns the short names of all plugins referenced by plugin="name@version" attributes.
+func requiredPlugins(config string) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, m := range pluginAttrRegex.FindAllStringSubmatch(config, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// referencedCredentials returns the credential IDs used by a config.
+func referencedCredentials(config string) []string {
+	ids := make([]string, 0)
+	for _, re := range []*regexp.Regexp{credentialsXMLRegex, credentialsDSLRegex} {
+		for _, m := range re.FindAllStringSubmatch(config, -1) {
+			if m[2] != "" && !strings.Contains(m[2], "${") {
+				ids = append(ids, m[2])
+			}
+		}
+	}
+	return ids
+}
+
+func isBelow(fullName string, folders map[string]bool) bool {
+	for parent, _ := splitFullName(fullName); parent != ""; parent, _ = splitFullName(parent) {
+		if folders[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// Migrate copies a folder subtree from one controller to another.
+// Configs are exported from src, URLs, credential IDs and node labels are
+// rewritten through the translation tables in opts, and the items are imported
+// on dst. Items that need plugins missing on dst are not migrated, together
+// with everything below them; credential IDs that cannot be found in the
+// global store of dst are reported as warnings.
+// Example: gojenkins.Migrate(ctx, old, new, &gojenkins.MigrateOptions{Root: "team"})
+func Migrate(ctx context.Context, src, dst *Jenkins, opts *MigrateOptions) (*MigrationReport, error) {
+	if opts == nil {
+		opts = &MigrateOptions{}
+	}
+	var buf bytes.Buffer
+	if _, err := src.ExportItems(ctx, opts.Root, &buf); err != nil {
+		return nil, fmt.Errorf("exporting from source: %w", err)
+	}
+	manifest, configs, err := ReadExportArchive(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins, err := dst.GetPlugins(ctx, 1)
+	if err != nil {
+		return nil, fmt.Errorf("listing destination plugins: %w", err)
+	}
+
+	report := &MigrationReport{
+		Imported:       make([]ImportResult, 0),
+		NotMigrated:    make([]MigrationIssue, 0),
+		Warnings:       make([]MigrationIssue, 0),
+		MissingPlugins: make([]string, 0),
+	}
+
+	var knownCredentials map[string]bool
+	cm := CredentialsManager{J: dst}
+	if ids, err := cm.List(ctx, "_"); err == nil {
+		knownCredentials = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			knownCredentials[id] = true
+		}
+	} else {
+		report.Warnings = append(report.Warnings, MigrationIssue{
+			Reason: fmt.Sprintf("could not list destination credentials, references were not checked: %v", err),
+		})
+	}
+
+	rw := newConfigRewriter(src, dst, opts)
+	missingPlugins := make(map[string]bool)
+	blocked := make(map[string]bool)
+	kept := make([]ExportedItem, 0, len(manifest.Items))
+	for _, item := range manifest.Items {
+		if isBelow(item.FullName, blocked) || (item.Kind == ItemKindView && blocked[item.Parent]) {
+			report.NotMigrated = append(report.NotMigrated, MigrationIssue{
+				Kind: item.Kind, FullName: item.FullName, Reason: "parent folder was not migrated",
+			})
+			continue
+		}
+
+		config := rw.rewrite(configs[item.Path])
+		var missing []string
+		for _, name := range requiredPlugins(config) {
+			if plugins.Contains(name) == nil {
+				missing = append(missing, name)
+				if !missingPlugins[name] {
+					missingPlugins[name] = true
+					report.MissingPlugins = append(report.MissingPlugins, name)
+				}
+			}
+		}
+		if len(missing) > 0 {
+			if item.Kind == ItemKindFolder {
+				blocked[item.FullName] = true
+			}
+			report.NotMigrated = append(report.NotMigrated, MigrationIssue{
+				Kind: item.Kind, FullName: item.FullName,
+				Reason: "missing plugins on destination: " + strings.Join(missing, ", "),
+			})
+			continue
+		}
+
+		if knownCredentials != nil {
+			for _, id := range referencedCredentials(config) {
+				if !knownCredentials[id] {
+					report.Warnings = append(report.Warnings, MigrationIssue{
+						Kind: item.Kind, FullName: item.FullName,
+						Reason: fmt.Sprintf("credential %q not found on destination", id),
+					})
+				}
+			}
+		}
+
+		sum := sha256.Sum256([]byte(config))
+		item.SHA256 = hex.EncodeToString(sum[:])
+		configs[item.Path] = config
+		kept = append(kept, item)
+	}
+	sort.Strings(report.MissingPlugins)
+
+	if opts.DryRun {
+		return report, nil
+	}
+	filtered := *manifest
+	filtered.Items = kept
+	results, err := dst.importConfigs(ctx, &filtered, configs, &opts.ImportOptions)
+	report.Imported = append(report.Imported, results...)
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
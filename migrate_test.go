@@ -0,0 +1,149 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateLabelExpr(t *testing.T) {
+	table := map[string]string{"linux": "ubuntu-22", "docker": "container"}
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"linux", "ubuntu-22"},
+		{"linux && docker", "ubuntu-22 && container"},
+		{"!linux||(docker&&arm64)", "!ubuntu-22||(container&&arm64)"},
+		{"linux-x64 -> docker", "linux-x64 -> container"},
+		{"windows", "windows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			assert.Equal(t, tt.expected, translateLabelExpr(tt.expr, table))
+		})
+	}
+}
+
+func TestConfigRewriter_Rewrite(t *testing.T) {
+	src := &Jenkins{Server: "https://old.example.com"}
+	dst := &Jenkins{Server: "https://new.example.com"}
+	rw := newConfigRewriter(src, dst, &MigrateOptions{
+		URLs:        map[string]string{"https://git.old.example.com": "https://git.example.com"},
+		Credentials: map[string]string{"deploy-key": "deploy-key-v2"},
+		Labels:      map[string]string{"linux": "ubuntu"},
+	})
+
+	config := `<project>
+  <description>see https://old.example.com/job/x</description>
+  <url>https://git.old.example.com/repo.git</url>
+  <credentialsId>deploy-key</credentialsId>
+  <assignedNode>linux &amp;&amp; docker</assignedNode>
+  <script>node(&apos;linux&apos;) { git credentialsId: &apos;deploy-key&apos;, url: &apos;x&apos; }</script>
+</project>`
+	out := rw.rewrite(config)
+
+	assert.Contains(t, out, "see https://new.example.com/job/x")
+	assert.Contains(t, out, "<url>https://git.example.com/repo.git</url>")
+	assert.Contains(t, out, "<credentialsId>deploy-key-v2</credentialsId>")
+	assert.Contains(t, out, "<assignedNode>ubuntu &amp;&amp; docker</assignedNode>")
+	assert.Contains(t, out, "node(&apos;ubuntu&apos;)")
+	assert.Contains(t, out, "credentialsId: &apos;deploy-key-v2&apos;")
+}
+
+func TestRequiredPlugins(t *testing.T) {
+	config := `<flow-definition plugin="workflow-job@1.0"><definition plugin="workflow-cps@2.0"/><x plugin="workflow-job@1.0"/></flow-definition>`
+	assert.Equal(t, []string{"workflow-job", "workflow-cps"}, requiredPlugins(config))
+}
+
+func TestMigrate(t *testing.T) {
+	src := newExportMock()
+	src.Server = "https://old.example.com"
+	src.Requester.(*MockRequester).GetXMLFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		config := `<folder plugin="cloudbees-folder@6.0"/>`
+		if strings.Contains(endpoint, "/job/api/") {
+			config = `<flow-definition plugin="workflow-job@2.0"><credentialsId>old-id</credentialsId>` +
+				`<url>https://old.example.com/job/team</url></flow-definition>`
+		}
+		if strings.Contains(endpoint, "/view/") {
+			config = `<hudson.model.ListView/>`
+		}
+		*response.(*string) = config
+		return &http.Response{StatusCode: 200}, nil
+	}
+
+	dst := newMockJenkins()
+	dst.Server = "https://new.example.com"
+	mock := dst.Requester.(*MockRequester)
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		if p, ok := response.(*PluginResponse); ok {
+			p.Plugins = []Plugin{{ShortName: "cloudbees-folder"}, {ShortName: "workflow-job"}}
+		}
+		if query["tree"] == "_class" {
+			return &http.Response{StatusCode: 404}, nil
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		if ids, ok := response.(*credentialIDs); ok {
+			ids.Credentials = []credentialID{{ID: "new-id"}}
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.PostFunc = func(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+		return &http.Response{StatusCode: 200}, nil
+	}
+	posted := make(map[string]string)
+	mock.PostXMLFunc = func(ctx context.Context, endpoint string, xml string, response interface{}, query map[string]string) (*http.Response, error) {
+		posted[endpoint+query["name"]] = xml
+		return &http.Response{StatusCode: 200}, nil
+	}
+
+	report, err := Migrate(context.Background(), src, dst, &MigrateOptions{
+		Root:        "team",
+		Credentials: map[string]string{"old-id": "new-id"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(report.Imported))
+	assert.Empty(t, report.NotMigrated)
+	assert.Empty(t, report.Warnings)
+	assert.Equal(t, `<flow-definition plugin="workflow-job@2.0"><credentialsId>new-id</credentialsId>`+
+		`<url>https://new.example.com/job/team</url></flow-definition>`, posted["/job/team/createItemapi"])
+}
+
+func TestMigrate_MissingPlugins(t *testing.T) {
+	src := newExportMock()
+	src.Requester.(*MockRequester).GetXMLFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		*response.(*string) = `<folder plugin="cloudbees-folder@6.0"/>`
+		return &http.Response{StatusCode: 200}, nil
+	}
+	dst := newMockJenkins()
+
+	report, err := Migrate(context.Background(), src, dst, &MigrateOptions{Root: "team", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cloudbees-folder"}, report.MissingPlugins)
+	require.Equal(t, 3, len(report.NotMigrated))
+	assert.Equal(t, "team", report.NotMigrated[0].FullName)
+	assert.Equal(t, "parent folder was not migrated", report.NotMigrated[1].Reason)
+	assert.Equal(t, "parent folder was not migrated", report.NotMigrated[2].Reason)
+	assert.Empty(t, report.Imported)
+}
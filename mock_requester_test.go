@@ -35,6 +35,7 @@ type MockRequester struct {
 	PostFilesFunc func(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string, files []string) (*http.Response, error)
 	GetFunc       func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error)
 	GetXMLFunc    func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error)
+	GetRangeFunc  func(ctx context.Context, endpoint string, offset int64) (*http.Response, error)
 }
 
 // GetJSON implements JenkinsRequester.
@@ -142,8 +143,26 @@ func (m *MockRequester) GetXML(ctx context.Context, endpoint string, response in
 	return &http.Response{StatusCode: 200}, nil
 }
 
-// Ensure MockRequester implements JenkinsRequester
-var _ JenkinsRequester = (*MockRequester)(nil)
+// GetRange implements RangeRequester.
+func (m *MockRequester) GetRange(ctx context.Context, endpoint string, offset int64) (*http.Response, error) {
+	m.lastEndpoint = endpoint
+	if m.GetRangeFunc != nil {
+		return m.GetRangeFunc(ctx, endpoint, offset)
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.response != nil {
+		return m.response, nil
+	}
+	return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+}
+
+// Ensure MockRequester implements JenkinsRequester and RangeRequester
+var (
+	_ JenkinsRequester = (*MockRequester)(nil)
+	_ RangeRequester   = (*MockRequester)(nil)
+)
 
 // newMockJenkins creates a Jenkins instance with a mock requester for testing.
 func newMockJenkins() *Jenkins {
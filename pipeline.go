@@ -18,13 +18,52 @@
 package gojenkins
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
 )
 
 var baseURLRegex *regexp.Regexp
 
+// how often Replay and RestartFromStage check whether the new build has started
+var replayPollInterval = time.Second
+
+const (
+	replayCauseClass  = "org.jenkinsci.plugins.workflow.cps.replay.ReplayCause"
+	restartCauseClass = "org.jenkinsci.plugins.pipeline.modeldefinition.causes.RestartDeclarativePipelineCause"
+)
+
+// Reasons reported by ErrRestartFromStage.
+const (
+	RestartNotDeclarative      = "not a declarative pipeline"
+	RestartStillRunning        = "run is still in progress"
+	RestartStageNotRestartable = "stage is not restartable"
+)
+
+// ErrRestartFromStage occurs when a pipeline run cannot be restarted from a stage.
+type ErrRestartFromStage struct {
+	Run    string
+	Stage  string
+	Reason string
+}
+
+func (e *ErrRestartFromStage) Error() string {
+	if e.Stage != "" {
+		return fmt.Sprintf("cannot restart run %s from stage %q: %s", e.Run, e.Stage, e.Reason)
+	}
+	return fmt.Sprintf("cannot restart run %s: %s", e.Run, e.Reason)
+}
+
 func init() {
 	var err error
 	baseURLRegex, err = regexp.Compile("(.+)/wfapi/.*$")
@@ -59,14 +98,52 @@ type PipelineNode struct {
 	Duration       int64 `json:"durationMillis"`
 	StageFlowNodes []PipelineNode
 	ParentNodes    []int64
+	// ParameterDescription is the main argument of a step, e.g. the script
+	// of sh.
+	ParameterDescription string             `json:"parameterDescription"`
+	Error                *PipelineNodeError `json:"error"`
+}
+
+// PipelineNodeError is the error a failed node ended with.
+type PipelineNodeError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
 }
 
 // PipelineInputAction represents a pending input action that requires user interaction.
 type PipelineInputAction struct {
-	ID         string
-	Message    string
-	ProceedURL string
-	AbortURL   string
+	ID          string
+	Message     string
+	ProceedText string
+	ProceedURL  string
+	AbortURL    string
+	Inputs      []PipelineInputParameter
+	// Submitter is the comma separated list of users and groups allowed to
+	// answer, empty when anyone with the Build permission may. wfapi does
+	// not report it, it is read from the InputAction of the run, and left
+	// empty too when that cannot be read.
+	Submitter string `json:"-"`
+	// SubmitterParameter is the parameter the input stores the name of the
+	// user who answered in.
+	SubmitterParameter string `json:"-"`
+}
+
+// PipelineInputParameter is a parameter asked for by an input step.
+// Definition is the parameter definition as serialized by Jenkins, e.g.
+// with the defaultParameterValue and the choices of a choice parameter.
+type PipelineInputParameter struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Definition  map[string]interface{} `json:"definition"`
+}
+
+// ReplayScripts holds the scripts of a pipeline run as shown on its Replay page.
+// LoadedScripts is keyed by the form field of each script loaded with `load`,
+// which is its class name with dots replaced by underscores.
+type ReplayScripts struct {
+	MainScript    string
+	LoadedScripts map[string]string
 }
 
 // PipelineArtifact represents an artifact produced by a pipeline run.
@@ -139,6 +216,9 @@ func (pr *PipelineRun) GetPendingInputActions(ctx context.Context) (PIAs []Pipel
 	if err != nil {
 		return nil, err
 	}
+	if len(PIAs) > 0 {
+		pr.setSubmitters(ctx, PIAs)
+	}
 
 	return PIAs, nil
 }
@@ -163,6 +243,8 @@ func (pr *PipelineRun) GetNode(ctx context.Context, id string) (node *PipelineNo
 	if err != nil {
 		return nil, err
 	}
+	node.Run = pr
+	node.Base = pr.Base + "/execution/node/" + id
 
 	return node, nil
 }
@@ -171,7 +253,6 @@ func (pr *PipelineRun) GetNode(ctx context.Context, id string) (node *PipelineNo
 func (node *PipelineNode) GetLog(ctx context.Context) (log *PipelineNodeLog, err error) {
 	log = new(PipelineNodeLog)
 	href := node.Base + "/wfapi/log"
-	fmt.Println(href)
 	_, err = node.Run.Job.Jenkins.Requester.GetJSON(ctx, href, log, nil)
 	if err != nil {
 		return nil, err
@@ -179,3 +260,311 @@ func (node *PipelineNode) GetLog(ctx context.Context) (log *PipelineNodeLog, err
 
 	return log, nil
 }
+
+// GetReplayScripts returns the main script and the loaded scripts of the run,
+// as they would be offered for editing on the Replay page.
+func (pr *PipelineRun) GetReplayScripts(ctx context.Context) (*ReplayScripts, error) {
+	var page string
+	resp, err := pr.Job.Jenkins.Requester.Get(ctx, pr.Base+"/replay", &page, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("run %s cannot be replayed: status %d", pr.ID, resp.StatusCode)
+	}
+	return parseReplayPage(strings.NewReader(page))
+}
+
+// parseReplayPage extracts the script textareas of the replay form.
+func parseReplayPage(r io.Reader) (*ReplayScripts, error) {
+	scripts := &ReplayScripts{LoadedScripts: make(map[string]string)}
+	found := false
+	z := html.NewTokenizer(r)
+	field := ""
+	var text strings.Builder
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return nil, z.Err()
+			}
+			if !found {
+				return nil, errors.New("no replay form found")
+			}
+			return scripts, nil
+		case html.StartTagToken:
+			tn, hasAttr := z.TagName()
+			if string(tn) == "textarea" && hasAttr {
+				if name := attr(z)["name"]; strings.HasPrefix(name, "_.") {
+					field = strings.TrimPrefix(name, "_.")
+					text.Reset()
+				}
+			}
+		case html.TextToken:
+			if field != "" {
+				text.Write(z.Text())
+			}
+		case html.EndTagToken:
+			tn, _ := z.TagName()
+			if string(tn) != "textarea" || field == "" {
+				continue
+			}
+			// browsers drop the newline right after <textarea>
+			content := strings.TrimPrefix(text.String(), "\n")
+			if field == "mainScript" {
+				scripts.MainScript = content
+				found = true
+			} else {
+				scripts.LoadedScripts[field] = content
+			}
+			field = ""
+		}
+	}
+}
+
+// Replay starts a new run of the pipeline with a modified main script and
+// loaded scripts, like the Replay action of the UI.
+// An empty mainScript keeps the current one, and loaded scripts missing from
+// libraryScripts are replayed unchanged. libraryScripts may be keyed by class
+// name or by form field name, see ReplayScripts.
+// Jenkins does not report which build it started, so Replay waits until a
+// build caused by replaying this run shows up, its queue item is cancelled,
+// or ctx is done. ctx should have a deadline.
+func (pr *PipelineRun) Replay(ctx context.Context, mainScript string, libraryScripts map[string]string) (*Build, error) {
+	original, err := strconv.ParseInt(pr.ID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run id %q: %w", pr.ID, err)
+	}
+	current, err := pr.GetReplayScripts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := make(map[string]string, len(current.LoadedScripts)+1)
+	for field, script := range current.LoadedScripts {
+		form[field] = script
+	}
+	for name, script := range libraryScripts {
+		field := strings.ReplaceAll(name, ".", "_")
+		if _, ok := current.LoadedScripts[field]; !ok {
+			return nil, fmt.Errorf("run %s did not load script %q", pr.ID, name)
+		}
+		form[field] = script
+	}
+	form["mainScript"] = current.MainScript
+	if mainScript != "" {
+		form["mainScript"] = mainScript
+	}
+
+	job := pr.Job
+	if _, err := job.Poll(ctx); err != nil {
+		return nil, err
+	}
+	next := job.Raw.NextBuildNumber
+
+	data := url.Values{}
+	data.Set("json", makeJson(form))
+	resp, err := job.Jenkins.Requester.Post(ctx, pr.Base+"/replay/run", bytes.NewBufferString(data.Encode()), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("replay of run %s failed: status %d", pr.ID, resp.StatusCode)
+	}
+	return job.waitForBuild(ctx, next, func(causes []map[string]interface{}) bool {
+		return causedBy(causes, replayCauseClass, "originalNumber", original)
+	})
+}
+
+// waitForBuild polls the job from build number `from` onwards until it finds a
+// build whose causes are accepted by match. It fails when the queue item of
+// such a build is cancelled; otherwise it only stops when ctx is done, so
+// callers must give ctx a deadline.
+func (j *Job) waitForBuild(ctx context.Context, from int64, match func(causes []map[string]interface{}) bool) (*Build, error) {
+	queued := make(map[int64]bool)
+	for {
+		if _, err := j.Poll(ctx); err != nil {
+			return nil, err
+		}
+		current, inQueue := queueItemID(j.Raw.QueueItem)
+		if inQueue {
+			queued[current] = true
+		}
+		for ; from <= j.Raw.LastBuild.Number; from++ {
+			build := &Build{Jenkins: j.Jenkins, Job: j, Raw: new(BuildResponse), Depth: 1, Base: j.Base + "/" + strconv.FormatInt(from, 10)}
+			if _, err := build.Poll(ctx); err != nil {
+				return nil, err
+			}
+			for _, a := range build.Raw.Actions {
+				if match(a.Causes) {
+					return build, nil
+				}
+			}
+		}
+		// items that left the queue either started a build or were cancelled
+		for id := range queued {
+			if inQueue && id == current {
+				continue
+			}
+			delete(queued, id)
+			item := new(taskResponse)
+			resp, err := j.Jenkins.Requester.GetJSON(ctx, j.Jenkins.getQueueItemURL(id), item, nil)
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				// Jenkins forgets items a few minutes after they left the queue
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !item.Cancelled {
+				continue
+			}
+			for _, a := range item.Actions {
+				if match(a.Causes) {
+					return nil, fmt.Errorf("queue item %d of the new build was cancelled", id)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(replayPollInterval):
+		}
+	}
+}
+
+// queueItemID returns the ID of the queueItem of a job, if it has one.
+func queueItemID(item interface{}) (int64, bool) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	id, ok := m["id"].(float64)
+	return int64(id), ok
+}
+
+// causedBy reports whether one of the causes is of the given class and has a
+// numeric field pointing at the original build number.
+func causedBy(causes []map[string]interface{}, class string, field string, original int64) bool {
+	for _, cause := range causes {
+		if cause["_class"] != class {
+			continue
+		}
+		if n, ok := cause[field].(float64); ok && int64(n) == original {
+			return true
+		}
+	}
+	return false
+}
+
+// RestartableStages returns the names of the stages the declarative pipeline
+// run can be restarted from. It fails with *ErrRestartFromStage when the run
+// is not a declarative pipeline or is still running.
+func (pr *PipelineRun) RestartableStages(ctx context.Context) ([]string, error) {
+	var state BuildResponse
+	if _, err := pr.Job.Jenkins.Requester.GetJSON(ctx, pr.Base, &state, map[string]string{"tree": "building"}); err != nil {
+		return nil, err
+	}
+	if state.Building {
+		return nil, &ErrRestartFromStage{Run: pr.ID, Reason: RestartStillRunning}
+	}
+
+	var page string
+	resp, err := pr.Job.Jenkins.Requester.Get(ctx, pr.Base+"/restart", &page, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &ErrRestartFromStage{Run: pr.ID, Reason: RestartNotDeclarative}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("restart page of run %s: status %d", pr.ID, resp.StatusCode)
+	}
+	return parseRestartPage(strings.NewReader(page)), nil
+}
+
+// parseRestartPage returns the options of the stageName select of the restart form.
+func parseRestartPage(r io.Reader) []string {
+	stages := make([]string, 0)
+	z := html.NewTokenizer(r)
+	inSelect, inOption := false, false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return stages
+		case html.StartTagToken:
+			tn, hasAttr := z.TagName()
+			switch string(tn) {
+			case "select":
+				inSelect = hasAttr && attr(z)["name"] == "stageName"
+			case "option":
+				if !inSelect {
+					continue
+				}
+				if value, ok := attr(z)["value"]; ok {
+					stages = append(stages, value)
+				} else {
+					inOption = true
+				}
+			}
+		case html.TextToken:
+			if inOption {
+				stages = append(stages, strings.TrimSpace(string(z.Text())))
+				inOption = false
+			}
+		case html.EndTagToken:
+			tn, _ := z.TagName()
+			if string(tn) == "select" {
+				inSelect = false
+			}
+			inOption = false
+		}
+	}
+}
+
+// RestartFromStage restarts a completed declarative pipeline run from the
+// named stage, skipping the stages before it.
+// It fails with *ErrRestartFromStage when the run is not declarative, is still
+// running or the stage is not restartable. Otherwise it waits until the new
+// build shows up and returns it, failing when its queue item is cancelled or
+// ctx is done. ctx should have a deadline.
+func (pr *PipelineRun) RestartFromStage(ctx context.Context, stageName string) (*Build, error) {
+	original, err := strconv.ParseInt(pr.ID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run id %q: %w", pr.ID, err)
+	}
+	stages, err := pr.RestartableStages(ctx)
+	if err != nil {
+		if e, ok := err.(*ErrRestartFromStage); ok {
+			e.Stage = stageName
+		}
+		return nil, err
+	}
+	restartable := false
+	for _, s := range stages {
+		restartable = restartable || s == stageName
+	}
+	if !restartable {
+		return nil, &ErrRestartFromStage{Run: pr.ID, Stage: stageName, Reason: RestartStageNotRestartable}
+	}
+
+	job := pr.Job
+	if _, err := job.Poll(ctx); err != nil {
+		return nil, err
+	}
+	next := job.Raw.NextBuildNumber
+
+	data := url.Values{}
+	data.Set("stageName", stageName)
+	data.Set("json", makeJson(map[string]string{"stageName": stageName}))
+	resp, err := job.Jenkins.Requester.Post(ctx, pr.Base+"/restart/restart", bytes.NewBufferString(data.Encode()), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("restart of run %s from stage %q failed: status %d", pr.ID, stageName, resp.StatusCode)
+	}
+	return job.waitForBuild(ctx, next, func(causes []map[string]interface{}) bool {
+		return causedBy(causes, restartCauseClass, "originRunNumber", original)
+	})
+}
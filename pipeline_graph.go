@@ -0,0 +1,225 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Kinds of FlowNode.
+const (
+	FlowRun    = "run"
+	FlowStage  = "stage"
+	FlowBranch = "parallel"
+	FlowStep   = "step"
+)
+
+// Statuses of pipeline nodes reported by wfapi.
+const (
+	PipelineStatusSuccess     = "SUCCESS"
+	PipelineStatusFailed      = "FAILED"
+	PipelineStatusUnstable    = "UNSTABLE"
+	PipelineStatusAborted     = "ABORTED"
+	PipelineStatusInProgress  = "IN_PROGRESS"
+	PipelineStatusNotExecuted = "NOT_EXECUTED"
+	PipelineStatusPaused      = "PAUSED_PENDING_INPUT"
+)
+
+// display name prefix of the node starting a parallel branch
+const parallelBranchPrefix = "Branch: "
+
+// FlowNode is a node of the flow graph of a pipeline run: the run itself,
+// a stage, a parallel branch or a step. Times are in milliseconds.
+type FlowNode struct {
+	ID          string             `json:"id"`
+	Kind        string             `json:"kind"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Status      string             `json:"status"`
+	StartTime   int64              `json:"startTimeMillis"`
+	Duration    int64              `json:"durationMillis"`
+	Error       *PipelineNodeError `json:"error,omitempty"`
+	// LogURL is the endpoint of the log of a step.
+	LogURL   string      `json:"logUrl,omitempty"`
+	Children []*FlowNode `json:"children,omitempty"`
+	// Node is the wfapi node, used to read its log.
+	Node *PipelineNode `json:"-"`
+}
+
+func newFlowNode(node *PipelineNode, kind string) *FlowNode {
+	fn := &FlowNode{
+		ID:          node.ID,
+		Kind:        kind,
+		Name:        node.Name,
+		Description: node.ParameterDescription,
+		Status:      node.Status,
+		StartTime:   node.StartTime,
+		Duration:    node.Duration,
+		Error:       node.Error,
+		LogURL:      node.URLs["log"]["href"],
+		Node:        node,
+	}
+	if kind == FlowBranch {
+		fn.Name = strings.TrimPrefix(fn.Name, parallelBranchPrefix)
+	}
+	return fn
+}
+
+// Failed returns true if the node failed.
+func (n *FlowNode) Failed() bool {
+	return n.Status == PipelineStatusFailed
+}
+
+// Walk calls fn for the node and all of its descendants, parents first.
+func (n *FlowNode) Walk(fn func(node *FlowNode)) {
+	fn(n)
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// FailedBranches returns the failed parallel branches below the node that
+// contain no failed branch themselves, i.e. the ones that caused the failure.
+func (n *FlowNode) FailedBranches() []*FlowNode {
+	failed := make([]*FlowNode, 0)
+	for _, c := range n.Children {
+		inner := c.FailedBranches()
+		if len(inner) == 0 && c.Kind == FlowBranch && c.Failed() {
+			inner = append(inner, c)
+		}
+		failed = append(failed, inner...)
+	}
+	return failed
+}
+
+// FlowGraph returns the run as a tree of stages, parallel branches and steps.
+// Stages declared inside another stage are nested below it. It walks the
+// wfapi describe endpoint of every stage, and of every parallel branch in
+// it, so it makes one request per stage and branch.
+func (pr *PipelineRun) FlowGraph(ctx context.Context) (*FlowNode, error) {
+	run := new(PipelineRun)
+	if _, err := pr.Job.Jenkins.Requester.GetJSON(ctx, pr.Base+"/wfapi/describe", run, nil); err != nil {
+		return nil, err
+	}
+	root := &FlowNode{ID: run.ID, Kind: FlowRun, Name: run.Name, Status: run.Status, StartTime: run.StartTime, Duration: run.Duration}
+	// wfapi lists nested stages along with the top-level ones
+	blocks := make([]*FlowNode, 0, len(run.Stages))
+	for _, stage := range run.Stages {
+		node, err := pr.flowNode(ctx, stage.ID, FlowStage)
+		if err != nil {
+			return nil, err
+		}
+		node.Walk(func(n *FlowNode) {
+			if n.Kind != FlowStep {
+				blocks = append(blocks, n)
+			}
+		})
+		if parent := enclosingBlock(blocks, node); parent != nil {
+			nestStage(parent, node)
+		} else {
+			root.Children = append(root.Children, node)
+		}
+	}
+	return root, nil
+}
+
+// enclosingBlock returns the innermost stage or branch whose flow nodes
+// include the start of the stage or one of its steps. Blocks start before
+// the blocks they enclose, so their IDs are lower.
+func enclosingBlock(blocks []*FlowNode, stage *FlowNode) *FlowNode {
+	own := map[string]bool{stage.ID: true}
+	for _, n := range stage.Node.StageFlowNodes {
+		own[n.ID] = true
+	}
+	var parent *FlowNode
+	for _, b := range blocks {
+		if flowNodeID(b.ID) >= flowNodeID(stage.ID) || (parent != nil && flowNodeID(b.ID) <= flowNodeID(parent.ID)) {
+			continue
+		}
+		for _, n := range b.Node.StageFlowNodes {
+			if own[n.ID] {
+				parent = b
+				break
+			}
+		}
+	}
+	return parent
+}
+
+// nestStage puts the stage in place of the steps of the parent that belong
+// to it.
+func nestStage(parent *FlowNode, stage *FlowNode) {
+	own := map[string]bool{stage.ID: true}
+	stage.Walk(func(n *FlowNode) { own[n.ID] = true })
+	children := make([]*FlowNode, 0, len(parent.Children))
+	nested := false
+	for _, c := range parent.Children {
+		if !own[c.ID] {
+			children = append(children, c)
+		} else if !nested {
+			children = append(children, stage)
+			nested = true
+		}
+	}
+	if !nested {
+		children = append(children, stage)
+	}
+	parent.Children = children
+}
+
+// flowNodeID returns the number of a flow node ID, which grows as the run
+// goes on.
+func flowNodeID(id string) int {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// flowNode describes a stage or branch and its children.
+func (pr *PipelineRun) flowNode(ctx context.Context, id string, kind string) (*FlowNode, error) {
+	node, err := pr.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	fn := newFlowNode(node, kind)
+	children := make([]*FlowNode, 0, len(node.StageFlowNodes))
+	inBranch := make(map[string]bool)
+	for i := range node.StageFlowNodes {
+		child := &node.StageFlowNodes[i]
+		if !strings.HasPrefix(child.Name, parallelBranchPrefix) {
+			child.Run = pr
+			child.Base = pr.Base + "/execution/node/" + child.ID
+			children = append(children, newFlowNode(child, FlowStep))
+			continue
+		}
+		branch, err := pr.flowNode(ctx, child.ID, FlowBranch)
+		if err != nil {
+			return nil, err
+		}
+		branch.Walk(func(n *FlowNode) { inBranch[n.ID] = true })
+		children = append(children, branch)
+	}
+	// wfapi also lists the steps of the branches in the stage
+	for _, c := range children {
+		if c.Kind == FlowBranch || !inBranch[c.ID] {
+			fn.Children = append(fn.Children, c)
+		}
+	}
+	return fn, nil
+}
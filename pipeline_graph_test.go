@@ -0,0 +1,148 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// a run with a Build stage and a Test stage running two branches in parallel
+var flowGraphEndpoints = map[string]string{
+	"/job/app/7/wfapi/describe": `{"id": "7", "name": "#7", "status": "FAILED", "startTimeMillis": 1000, "durationMillis": 60000,
+		"stages": [{"id": "6", "name": "Build"}, {"id": "12", "name": "Test"}]}`,
+	"/job/app/7/execution/node/6/wfapi/describe": `{"id": "6", "name": "Build", "status": "SUCCESS", "startTimeMillis": 1000, "durationMillis": 20000,
+		"stageFlowNodes": [{"id": "7", "name": "Shell Script", "status": "SUCCESS", "parameterDescription": "make",
+			"startTimeMillis": 1100, "durationMillis": 19000, "_links": {"log": {"href": "/job/app/7/execution/node/7/wfapi/log"}}}]}`,
+	"/job/app/7/execution/node/12/wfapi/describe": `{"id": "12", "name": "Test", "status": "FAILED", "startTimeMillis": 21000, "durationMillis": 40000,
+		"stageFlowNodes": [
+			{"id": "13", "name": "Print Message", "status": "SUCCESS"},
+			{"id": "15", "name": "Branch: unit", "status": "SUCCESS"},
+			{"id": "16", "name": "Branch: integration", "status": "FAILED"},
+			{"id": "18", "name": "Shell Script", "status": "SUCCESS"},
+			{"id": "19", "name": "Shell Script", "status": "FAILED"}]}`,
+	"/job/app/7/execution/node/15/wfapi/describe": `{"id": "15", "name": "Branch: unit", "status": "SUCCESS", "startTimeMillis": 22000, "durationMillis": 10000,
+		"stageFlowNodes": [{"id": "18", "name": "Shell Script", "status": "SUCCESS"}]}`,
+	"/job/app/7/execution/node/16/wfapi/describe": `{"id": "16", "name": "Branch: integration", "status": "FAILED", "startTimeMillis": 22000, "durationMillis": 39000,
+		"stageFlowNodes": [{"id": "19", "name": "Shell Script", "status": "FAILED", "parameterDescription": "./it.sh",
+			"error": {"message": "script returned exit code 1", "type": "hudson.AbortException"}}]}`,
+
+	// run 8 has a Check stage with the nested stages Lint and Unit, the
+	// latter running two branches in parallel
+	"/job/app/8/wfapi/describe": `{"id": "8", "name": "#8", "status": "SUCCESS", "startTimeMillis": 1000, "durationMillis": 50000,
+		"stages": [{"id": "6", "name": "Check"}, {"id": "9", "name": "Lint"}, {"id": "14", "name": "Unit"}, {"id": "30", "name": "Deploy"}]}`,
+	"/job/app/8/execution/node/6/wfapi/describe": `{"id": "6", "name": "Check", "status": "SUCCESS", "startTimeMillis": 1000, "durationMillis": 40000,
+		"stageFlowNodes": [
+			{"id": "7", "name": "Print Message", "status": "SUCCESS"},
+			{"id": "11", "name": "Shell Script", "status": "SUCCESS"},
+			{"id": "18", "name": "Branch: fast", "status": "SUCCESS"},
+			{"id": "19", "name": "Branch: slow", "status": "SUCCESS"},
+			{"id": "22", "name": "Shell Script", "status": "SUCCESS"},
+			{"id": "23", "name": "Shell Script", "status": "SUCCESS"}]}`,
+	"/job/app/8/execution/node/9/wfapi/describe": `{"id": "9", "name": "Lint", "status": "SUCCESS", "startTimeMillis": 2000, "durationMillis": 5000,
+		"stageFlowNodes": [{"id": "11", "name": "Shell Script", "status": "SUCCESS"}]}`,
+	"/job/app/8/execution/node/14/wfapi/describe": `{"id": "14", "name": "Unit", "status": "SUCCESS", "startTimeMillis": 8000, "durationMillis": 30000,
+		"stageFlowNodes": [
+			{"id": "18", "name": "Branch: fast", "status": "SUCCESS"},
+			{"id": "19", "name": "Branch: slow", "status": "SUCCESS"},
+			{"id": "22", "name": "Shell Script", "status": "SUCCESS"},
+			{"id": "23", "name": "Shell Script", "status": "SUCCESS"}]}`,
+	"/job/app/8/execution/node/18/wfapi/describe": `{"id": "18", "name": "Branch: fast", "status": "SUCCESS", "startTimeMillis": 9000, "durationMillis": 10000,
+		"stageFlowNodes": [{"id": "22", "name": "Shell Script", "status": "SUCCESS"}]}`,
+	"/job/app/8/execution/node/19/wfapi/describe": `{"id": "19", "name": "Branch: slow", "status": "SUCCESS", "startTimeMillis": 9000, "durationMillis": 28000,
+		"stageFlowNodes": [{"id": "23", "name": "Shell Script", "status": "SUCCESS"}]}`,
+	"/job/app/8/execution/node/30/wfapi/describe": `{"id": "30", "name": "Deploy", "status": "SUCCESS", "startTimeMillis": 41000, "durationMillis": 9000,
+		"stageFlowNodes": [{"id": "31", "name": "Shell Script", "status": "SUCCESS"}]}`,
+}
+
+// newNestedFlowGraphRun returns run 8, which has nested stages.
+func newNestedFlowGraphRun(t *testing.T) *PipelineRun {
+	run := newFlowGraphRun(t)
+	run.Base, run.ID = "/job/app/8", "8"
+	return run
+}
+
+func newFlowGraphRun(t *testing.T) *PipelineRun {
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		data, ok := flowGraphEndpoints[endpoint]
+		if !ok {
+			return &http.Response{StatusCode: 404}, assert.AnError
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), response))
+		return &http.Response{StatusCode: 200}, nil
+	}
+	job := &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/app"}
+	return &PipelineRun{Job: job, Base: "/job/app/7", ID: "7"}
+}
+
+func TestPipelineRun_FlowGraph(t *testing.T) {
+	root, err := newFlowGraphRun(t).FlowGraph(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, FlowRun, root.Kind)
+	require.Equal(t, 2, len(root.Children))
+
+	build := root.Children[0]
+	assert.Equal(t, FlowStage, build.Kind)
+	require.Equal(t, 1, len(build.Children))
+	assert.Equal(t, "make", build.Children[0].Description)
+	assert.Equal(t, "/job/app/7/execution/node/7/wfapi/log", build.Children[0].LogURL)
+	assert.Equal(t, "/job/app/7/execution/node/7", build.Children[0].Node.Base)
+
+	test := root.Children[1]
+	kinds := make([]string, len(test.Children))
+	for i, c := range test.Children {
+		kinds[i] = c.Kind + ":" + c.Name
+	}
+	// the steps of the branches only appear below the branches
+	assert.Equal(t, []string{"step:Print Message", "parallel:unit", "parallel:integration"}, kinds)
+
+	failed := root.FailedBranches()
+	require.Equal(t, 1, len(failed))
+	assert.Equal(t, "integration", failed[0].Name)
+	assert.Equal(t, "script returned exit code 1", failed[0].Children[0].Error.Message)
+
+	count := 0
+	root.Walk(func(*FlowNode) { count++ })
+	assert.Equal(t, 9, count)
+}
+
+func TestPipelineRun_FlowGraph_NestedStages(t *testing.T) {
+	root, err := newNestedFlowGraphRun(t).FlowGraph(context.Background())
+	require.NoError(t, err)
+
+	var shape func(n *FlowNode) string
+	shape = func(n *FlowNode) string {
+		s := n.Kind + ":" + n.Name
+		if len(n.Children) == 0 {
+			return s
+		}
+		children := make([]string, len(n.Children))
+		for i, c := range n.Children {
+			children[i] = shape(c)
+		}
+		return s + "(" + strings.Join(children, " ") + ")"
+	}
+	assert.Equal(t, "run:#8("+
+		"stage:Check(step:Print Message stage:Lint(step:Shell Script) "+
+		"stage:Unit(parallel:fast(step:Shell Script) parallel:slow(step:Shell Script))) "+
+		"stage:Deploy(step:Shell Script))", shape(root))
+}
@@ -0,0 +1,188 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// interval between two checks of WaitForInput
+var inputPollInterval = 2 * time.Second
+
+// ErrNoPendingInput occurs when a run has no pending input with the ID asked for.
+type ErrNoPendingInput struct {
+	Run     string
+	InputID string
+}
+
+func (e *ErrNoPendingInput) Error() string {
+	if e.InputID == "" {
+		return fmt.Sprintf("run %s has no pending input", e.Run)
+	}
+	return fmt.Sprintf("run %s has no pending input %q", e.Run, e.InputID)
+}
+
+// pendingInput returns the pending input with the given ID, compared
+// without case as Jenkins capitalizes it, or the first one when inputID
+// is empty.
+func (pr *PipelineRun) pendingInput(ctx context.Context, inputID string) (*PipelineInputAction, error) {
+	actions, err := pr.GetPendingInputActions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range actions {
+		if inputID == "" || strings.EqualFold(actions[i].ID, inputID) {
+			return &actions[i], nil
+		}
+	}
+	return nil, &ErrNoPendingInput{Run: pr.ID, InputID: inputID}
+}
+
+// setSubmitters fills in who may answer the inputs from the InputAction of
+// the run, as wfapi leaves it out. It is best effort: when the run cannot be
+// read, e.g. for lack of permission, the submitters stay empty.
+func (pr *PipelineRun) setSubmitters(ctx context.Context, actions []PipelineInputAction) {
+	var run struct {
+		Actions []struct {
+			Executions []struct {
+				ID    string `json:"id"`
+				Input struct {
+					Submitter          string `json:"submitter"`
+					SubmitterParameter string `json:"submitterParameter"`
+				} `json:"input"`
+			} `json:"executions"`
+		} `json:"actions"`
+	}
+	tree := "actions[executions[id,input[submitter,submitterParameter]]]"
+	if _, err := pr.Job.Jenkins.Requester.GetJSON(ctx, pr.Base, &run, map[string]string{"tree": tree}); err != nil {
+		return
+	}
+	for _, a := range run.Actions {
+		for _, e := range a.Executions {
+			for i := range actions {
+				if strings.EqualFold(actions[i].ID, e.ID) {
+					actions[i].Submitter = e.Input.Submitter
+					actions[i].SubmitterParameter = e.Input.SubmitterParameter
+				}
+			}
+		}
+	}
+}
+
+// ProceedInput submits the first pending input action for a pipeline run.
+func (pr *PipelineRun) ProceedInput(ctx context.Context) (bool, error) {
+	return pr.SubmitInput(ctx, "", nil)
+}
+
+// SubmitInput answers the pending input with the given ID, or the first
+// one when inputID is empty, with values for its parameters. Parameters
+// left out are sent with their default value.
+func (pr *PipelineRun) SubmitInput(ctx context.Context, inputID string, params map[string]interface{}) (bool, error) {
+	action, err := pr.pendingInput(ctx, inputID)
+	if err != nil {
+		return false, err
+	}
+	known := make(map[string]bool, len(action.Inputs))
+	for _, p := range action.Inputs {
+		known[p.Name] = true
+	}
+	type inputValue struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	}
+	values := make([]inputValue, 0, len(action.Inputs))
+	for name, value := range params {
+		if !known[name] {
+			return false, fmt.Errorf("input %s has no parameter %q", action.ID, name)
+		}
+		values = append(values, inputValue{Name: name, Value: value})
+	}
+	// inputSubmit leaves out parameters which are not sent
+	for _, p := range action.Inputs {
+		if _, ok := params[p.Name]; ok {
+			continue
+		}
+		if def, ok := p.Definition["defaultParameterValue"].(map[string]interface{}); ok {
+			values = append(values, inputValue{Name: p.Name, Value: def["value"]})
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+
+	data := url.Values{}
+	data.Set("inputId", action.ID)
+	data.Set("json", makeJson(map[string]interface{}{"parameter": values}))
+	resp, err := pr.Job.Jenkins.Requester.Post(ctx, pr.Base+"/wfapi/inputSubmit", bytes.NewBufferString(data.Encode()), nil, nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("submitting input %s failed: status %d", action.ID, resp.StatusCode)
+	}
+	return true, nil
+}
+
+// AbortInput aborts the pending input with the given ID, or the first one
+// when inputID is empty, which fails the run.
+func (pr *PipelineRun) AbortInput(ctx context.Context, inputID string) (bool, error) {
+	action, err := pr.pendingInput(ctx, inputID)
+	if err != nil {
+		return false, err
+	}
+	data := url.Values{}
+	data.Set("json", makeJson(map[string]string{}))
+
+	href := pr.Base + "/input/" + url.PathEscape(action.ID) + "/abort"
+	resp, err := pr.Job.Jenkins.Requester.Post(ctx, href, bytes.NewBufferString(data.Encode()), nil, nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("aborting input %s failed: status %d", action.ID, resp.StatusCode)
+	}
+	return true, nil
+}
+
+// WaitForInput blocks until the run has a pending input and returns it.
+// It fails when the run finishes without asking for input.
+func (pr *PipelineRun) WaitForInput(ctx context.Context) (*PipelineInputAction, error) {
+	for {
+		actions, err := pr.GetPendingInputActions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(actions) > 0 {
+			return &actions[0], nil
+		}
+		run := new(PipelineRun)
+		if _, err := pr.Job.Jenkins.Requester.GetJSON(ctx, pr.Base+"/wfapi/describe", run, nil); err != nil {
+			return nil, err
+		}
+		if run.Status != PipelineStatusInProgress && run.Status != PipelineStatusPaused {
+			return nil, fmt.Errorf("run %s finished with status %s without asking for input", pr.ID, run.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(inputPollInterval):
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pendingInputs = `[
+	{"id": "Approve", "message": "Ship it?", "proceedText": "Ship", "proceedUrl": "/job/app/3/wfapi/inputSubmit?inputId=Approve",
+		"abortUrl": "/job/app/3/input/Approve/abort",
+		"inputs": [{"type": "ChoiceParameterDefinition", "name": "TARGET", "description": "Where to",
+			"definition": {"choices": ["staging", "prod"], "defaultParameterValue": {"name": "TARGET", "value": "staging"}}},
+			{"type": "BooleanParameterDefinition", "name": "NOTIFY",
+			"definition": {"defaultParameterValue": {"name": "NOTIFY", "value": true}}}]},
+	{"id": "Deploy", "message": "Deploy?", "proceedUrl": "/job/app/3/wfapi/inputSubmit?inputId=Deploy", "abortUrl": "/job/app/3/input/Deploy/abort"}
+]`
+
+// newInputRun returns a run whose pending inputs are returned by inputs,
+// recording the form of every POST in posts.
+func newInputRun(t *testing.T, inputs func() string, posts map[string]url.Values) *PipelineRun {
+	jenkins := newMockJenkins()
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		switch endpoint {
+		case "/job/app/3/wfapi/pendingInputActions":
+			require.NoError(t, json.Unmarshal([]byte(inputs()), response))
+		case "/job/app/3":
+			assert.Equal(t, "actions[executions[id,input[submitter,submitterParameter]]]", query["tree"])
+			run := `{"actions": [{}, {"executions": [{"id": "Approve", "input": {"submitter": "release-managers", "submitterParameter": "APPROVER"}}]}]}`
+			require.NoError(t, json.Unmarshal([]byte(run), response))
+		case "/job/app/3/wfapi/describe":
+			require.NoError(t, json.Unmarshal([]byte(`{"id": "3", "status": "IN_PROGRESS"}`), response))
+		default:
+			return &http.Response{StatusCode: 404}, assert.AnError
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.PostFunc = func(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+		body, err := io.ReadAll(payload)
+		require.NoError(t, err)
+		posts[endpoint], err = url.ParseQuery(string(body))
+		require.NoError(t, err)
+		return &http.Response{StatusCode: 200}, nil
+	}
+	job := &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/app"}
+	return &PipelineRun{Job: job, Base: "/job/app/3", ID: "3"}
+}
+
+func TestPipelineRun_GetPendingInputActions_Parameters(t *testing.T) {
+	run := newInputRun(t, func() string { return pendingInputs }, nil)
+	actions, err := run.GetPendingInputActions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(actions))
+	assert.Equal(t, "release-managers", actions[0].Submitter)
+	assert.Equal(t, "APPROVER", actions[0].SubmitterParameter)
+	assert.Empty(t, actions[1].Submitter)
+	assert.Equal(t, "Ship", actions[0].ProceedText)
+	assert.Equal(t, "TARGET", actions[0].Inputs[0].Name)
+	assert.Equal(t, []interface{}{"staging", "prod"}, actions[0].Inputs[0].Definition["choices"])
+}
+
+func TestPipelineRun_GetPendingInputActions_SubmittersForbidden(t *testing.T) {
+	run := newInputRun(t, func() string { return pendingInputs }, nil)
+	mock := run.Job.Jenkins.Requester.(*MockRequester)
+	get := mock.GetJSONFunc
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		if endpoint == "/job/app/3" {
+			return &http.Response{StatusCode: 403}, assert.AnError
+		}
+		return get(ctx, endpoint, response, query)
+	}
+
+	actions, err := run.GetPendingInputActions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(actions))
+	assert.Empty(t, actions[0].Submitter)
+	assert.Equal(t, "Approve", actions[0].ID)
+}
+
+func TestPipelineRun_SubmitInput(t *testing.T) {
+	posts := make(map[string]url.Values)
+	run := newInputRun(t, func() string { return pendingInputs }, posts)
+
+	ok, err := run.SubmitInput(context.Background(), "approve", map[string]interface{}{"TARGET": "prod"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	form := posts["/job/app/3/wfapi/inputSubmit"]
+	assert.Equal(t, "Approve", form.Get("inputId"))
+	assert.JSONEq(t, `{"parameter": [{"name": "NOTIFY", "value": true}, {"name": "TARGET", "value": "prod"}]}`, form.Get("json"))
+
+	// parameters left out are sent with their default
+	_, err = run.SubmitInput(context.Background(), "Approve", nil)
+	require.NoError(t, err)
+	form = posts["/job/app/3/wfapi/inputSubmit"]
+	assert.JSONEq(t, `{"parameter": [{"name": "NOTIFY", "value": true}, {"name": "TARGET", "value": "staging"}]}`, form.Get("json"))
+
+	_, err = run.SubmitInput(context.Background(), "Deploy", map[string]interface{}{"TARGET": "prod"})
+	assert.EqualError(t, err, `input Deploy has no parameter "TARGET"`)
+
+	_, err = run.SubmitInput(context.Background(), "Rollback", nil)
+	var missing *ErrNoPendingInput
+	assert.ErrorAs(t, err, &missing)
+}
+
+func TestPipelineRun_AbortInput(t *testing.T) {
+	posts := make(map[string]url.Values)
+	run := newInputRun(t, func() string { return pendingInputs }, posts)
+
+	ok, err := run.AbortInput(context.Background(), "Deploy")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Contains(t, posts, "/job/app/3/input/Deploy/abort")
+}
+
+func TestPipelineRun_ProceedInput_NoPendingInput(t *testing.T) {
+	run := newInputRun(t, func() string { return `[]` }, nil)
+
+	// used to panic on actions[0]
+	ok, err := run.ProceedInput(context.Background())
+	assert.False(t, ok)
+	assert.EqualError(t, err, "run 3 has no pending input")
+	_, err = run.AbortInput(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestPipelineRun_WaitForInput(t *testing.T) {
+	inputPollInterval = time.Millisecond
+	defer func() { inputPollInterval = 2 * time.Second }()
+
+	polls := 0
+	run := newInputRun(t, func() string {
+		polls++
+		if polls < 3 {
+			return `[]`
+		}
+		return pendingInputs
+	}, nil)
+
+	action, err := run.WaitForInput(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Approve", action.ID)
+	assert.Equal(t, 3, polls)
+}
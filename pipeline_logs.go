@@ -0,0 +1,175 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stepLog reads the log of a step from offset. Steps that print nothing
+// have no log, which reads as empty.
+func (pr *PipelineRun) stepLog(ctx context.Context, id string, offset int64) (consoleResponse, error) {
+	endpoint := pr.Base + "/execution/node/" + id + "/log/progressiveText"
+	console, err := progressiveText(ctx, pr.Job.Jenkins.Requester, endpoint, offset)
+	if errors.Is(err, errNoLog) {
+		return consoleResponse{Offset: offset}, nil
+	}
+	return console, err
+}
+
+// isRunning reports whether a wfapi status is the one of an unfinished node.
+func isRunning(status string) bool {
+	return status == PipelineStatusInProgress || status == PipelineStatusPaused
+}
+
+// StreamLog writes the log of the node to w, following it while the node
+// runs. For a stage or parallel branch the logs of its steps are written
+// as they start; the output of steps running in parallel is interleaved
+// chunk by chunk. The node must come from its run, e.g. GetNode or Stages.
+func (node *PipelineNode) StreamLog(ctx context.Context, w io.Writer) error {
+	pr := node.Run
+	if pr == nil {
+		return fmt.Errorf("node %s is not linked to a pipeline run", node.ID)
+	}
+	offsets := make(map[string]int64)
+	finished := make(map[string]bool)
+	interval := consoleMinInterval
+	for {
+		desc, err := pr.GetNode(ctx, node.ID)
+		if err != nil {
+			return err
+		}
+		steps := desc.StageFlowNodes
+		if len(steps) == 0 && desc.URLs["log"] != nil {
+			// a step has a log of its own
+			steps = []PipelineNode{*desc}
+		}
+
+		grew := false
+		for _, step := range steps {
+			if finished[step.ID] {
+				continue
+			}
+			log, err := pr.stepLog(ctx, step.ID, offsets[step.ID])
+			if err != nil {
+				return err
+			}
+			if log.Content != "" {
+				if _, err := io.WriteString(w, log.Content); err != nil {
+					return err
+				}
+				grew = true
+			}
+			offsets[step.ID] = log.Offset
+			finished[step.ID] = !log.HasMoreText && !isRunning(step.Status)
+		}
+		if !isRunning(desc.Status) {
+			return nil
+		}
+
+		if grew {
+			interval = consoleMinInterval
+		} else {
+			interval = min(interval*2, consoleMaxInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// DownloadLogs writes the logs of the run below dir, one file per stage and
+// parallel branch holding the logs of its steps. Files are laid out like
+// the flow graph: stage "Test" with branch "unit" gives "02-Test.log" for
+// the steps outside the branches and "02-Test/unit.log". Stages are
+// numbered to keep their order. Returns the paths written.
+func (pr *PipelineRun) DownloadLogs(ctx context.Context, dir string) ([]string, error) {
+	graph, err := pr.FlowGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	written := make([]string, 0)
+	for i, stage := range graph.Children {
+		name := fmt.Sprintf("%02d-%s", i+1, logFileName(stage))
+		if written, err = pr.downloadNodeLogs(ctx, stage, dir, name, written); err != nil {
+			return nil, err
+		}
+	}
+	return written, nil
+}
+
+// downloadNodeLogs writes dir/name.log with the steps of the node, then the
+// logs of its branches below dir/name.
+func (pr *PipelineRun) downloadNodeLogs(ctx context.Context, node *FlowNode, dir, name string, written []string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	target := filepath.Join(dir, name+".log")
+	f, err := os.Create(target)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range node.Children {
+		if c.Kind != FlowStep {
+			continue
+		}
+		log, err := pr.stepLog(ctx, c.ID, 0)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("log of step %s: %w", c.ID, err)
+		}
+		if _, err := io.WriteString(f, log.Content); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	written = append(written, target)
+
+	for _, c := range node.Children {
+		if c.Kind == FlowStep {
+			continue
+		}
+		if written, err = pr.downloadNodeLogs(ctx, c, filepath.Join(dir, name), logFileName(c), written); err != nil {
+			return nil, err
+		}
+	}
+	return written, nil
+}
+
+// logFileName turns the name of a node into a safe file name.
+func logFileName(node *FlowNode) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(node.Name))
+	if name == "" || name == "." || name == ".." {
+		return node.ID
+	}
+	return name
+}
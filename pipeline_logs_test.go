@@ -0,0 +1,119 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveStepLogs answers progressiveText requests of the steps from logs,
+// keyed by step ID. Steps without a log get a 404 like in Jenkins.
+func serveStepLogs(mock *MockRequester, logs func(id string) (string, bool)) {
+	mock.GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		id := strings.TrimSuffix(strings.TrimPrefix(endpoint, "/job/app/7/execution/node/"), "/log/progressiveText")
+		text, more := logs(id)
+		if text == "" && !more {
+			return &http.Response{StatusCode: 404, Header: http.Header{}}, nil
+		}
+		start, _ := strconv.Atoi(query["start"])
+		*response.(*string) = text[start:]
+		header := http.Header{"X-Text-Size": {strconv.Itoa(len(text))}}
+		if more {
+			header.Set("X-More-Data", "true")
+		}
+		return &http.Response{StatusCode: 200, Header: header}, nil
+	}
+}
+
+func TestPipelineNode_StreamLog(t *testing.T) {
+	run := newFlowGraphRun(t)
+	mock := run.Job.Jenkins.Requester.(*MockRequester)
+	polls := 0
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		polls++
+		status := PipelineStatusInProgress
+		if polls > 1 {
+			status = PipelineStatusSuccess
+		}
+		data := `{"id": "6", "name": "Build", "status": "` + status + `",
+			"stageFlowNodes": [{"id": "7", "name": "Shell Script", "status": "` + status + `"}]}`
+		require.NoError(t, json.Unmarshal([]byte(data), response))
+		return &http.Response{StatusCode: 200}, nil
+	}
+	serveStepLogs(mock, func(id string) (string, bool) {
+		if polls == 1 {
+			return "+ make\n", true
+		}
+		return "+ make\nok\n", false
+	})
+
+	node := &PipelineNode{Run: run, ID: "6"}
+	var out bytes.Buffer
+	require.NoError(t, node.StreamLog(context.Background(), &out))
+	assert.Equal(t, "+ make\nok\n", out.String())
+	assert.Equal(t, 2, polls)
+}
+
+func TestPipelineRun_DownloadLogs(t *testing.T) {
+	run := newFlowGraphRun(t)
+	stepLogs := map[string]string{
+		"7":  "+ make\n",
+		"18": "+ go test\nPASS\n",
+		"19": "+ ./it.sh\nFAIL\n",
+	}
+	serveStepLogs(run.Job.Jenkins.Requester.(*MockRequester), func(id string) (string, bool) {
+		return stepLogs[id], false
+	})
+
+	dir := t.TempDir()
+	written, err := run.DownloadLogs(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "01-Build.log"),
+		filepath.Join(dir, "02-Test.log"),
+		filepath.Join(dir, "02-Test", "unit.log"),
+		filepath.Join(dir, "02-Test", "integration.log"),
+	}, written)
+
+	data, err := os.ReadFile(filepath.Join(dir, "02-Test", "integration.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "+ ./it.sh\nFAIL\n", string(data))
+	// the print step has no log and the branch steps are in their own files
+	data, err = os.ReadFile(filepath.Join(dir, "02-Test.log"))
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestLogFileName(t *testing.T) {
+	assert.Equal(t, "lint_vet", logFileName(&FlowNode{ID: "3", Name: "lint/vet"}))
+	assert.Equal(t, "3", logFileName(&FlowNode{ID: "3", Name: ".."}))
+}
+
+func TestPipelineNode_StreamLog_NoRun(t *testing.T) {
+	node := &PipelineNode{ID: "7"}
+	var buf bytes.Buffer
+	assert.EqualError(t, node.StreamLog(context.Background(), &buf), "node 7 is not linked to a pipeline run")
+}
@@ -16,10 +16,15 @@ package gojenkins
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJob_GetPipelineRuns_Success(t *testing.T) {
@@ -344,3 +349,211 @@ func TestPipelineRun_Update(t *testing.T) {
 	assert.Equal(t, run, run.Stages[0].Run)
 	assert.Equal(t, "/job/test-pipeline/1/execution/node/10", run.Stages[0].Base)
 }
+
+const replayPage = `<html><body><form action="run" method="post">
+<textarea name="_.mainScript" class="workflow-editor">
+node { echo &quot;hello&quot; }</textarea>
+<textarea name="_.Script1">return this</textarea>
+</form></body></html>`
+
+func TestPipelineRun_GetReplayScripts(t *testing.T) {
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		assert.Equal(t, "/job/pipeline/3/replay", endpoint)
+		*response.(*string) = replayPage
+		return &http.Response{StatusCode: 200}, nil
+	}
+	run := &PipelineRun{Job: &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/pipeline"}, Base: "/job/pipeline/3", ID: "3"}
+
+	scripts, err := run.GetReplayScripts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, `node { echo "hello" }`, scripts.MainScript)
+	assert.Equal(t, map[string]string{"Script1": "return this"}, scripts.LoadedScripts)
+}
+
+func TestPipelineRun_Replay(t *testing.T) {
+	replayPollInterval = time.Millisecond
+	defer func() { replayPollInterval = time.Second }()
+
+	jenkins := newMockJenkins()
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		*response.(*string) = replayPage
+		return &http.Response{StatusCode: 200}, nil
+	}
+	replayed := false
+	var form url.Values
+	mock.PostFunc = func(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+		assert.Equal(t, "/job/pipeline/3/replay/run", endpoint)
+		body, _ := io.ReadAll(payload)
+		form, _ = url.ParseQuery(string(body))
+		replayed = true
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		switch r := response.(type) {
+		case *JobResponse:
+			r.NextBuildNumber = 5
+			r.LastBuild.Number = 4
+			if replayed {
+				r.LastBuild.Number = 6
+			}
+		case *BuildResponse:
+			// build 5 was started by someone else, build 6 is the replay
+			if strings.HasSuffix(endpoint, "/6") {
+				r.Number = 6
+				r.Actions = []generalObj{{Causes: []map[string]interface{}{
+					{"_class": replayCauseClass, "originalNumber": float64(3)},
+				}}}
+			}
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	run := &PipelineRun{Job: &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/pipeline"}, Base: "/job/pipeline/3", ID: "3"}
+
+	build, err := run.Replay(context.Background(), "", map[string]string{"Script1": "echo 'patched'"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), build.GetBuildNumber())
+	assert.Equal(t, "/job/pipeline/6", build.Base)
+	assert.JSONEq(t, `{"mainScript":"node { echo \"hello\" }","Script1":"echo 'patched'"}`, form.Get("json"))
+}
+
+func TestPipelineRun_Replay_Cancelled(t *testing.T) {
+	replayPollInterval = time.Millisecond
+	defer func() { replayPollInterval = time.Second }()
+
+	jenkins := newMockJenkins()
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		*response.(*string) = replayPage
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.PostFunc = func(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+		return &http.Response{StatusCode: 200}, nil
+	}
+	polls := 0
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		switch r := response.(type) {
+		case *JobResponse:
+			// the replay waits in the queue, then is cancelled
+			polls++
+			r.NextBuildNumber = 5
+			r.LastBuild.Number = 4
+			r.QueueItem = nil
+			if polls == 2 {
+				r.QueueItem = map[string]interface{}{"id": float64(77)}
+			}
+		case *taskResponse:
+			assert.Equal(t, "/queue/item/77", endpoint)
+			r.Cancelled = true
+			r.Actions = []generalAction{{Causes: []map[string]interface{}{
+				{"_class": replayCauseClass, "originalNumber": float64(3)},
+			}}}
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	run := &PipelineRun{Job: &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/pipeline"}, Base: "/job/pipeline/3", ID: "3"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := run.Replay(ctx, "", nil)
+	assert.EqualError(t, err, "queue item 77 of the new build was cancelled")
+}
+
+func TestPipelineRun_Replay_UnknownScript(t *testing.T) {
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		*response.(*string) = replayPage
+		return &http.Response{StatusCode: 200}, nil
+	}
+	run := &PipelineRun{Job: &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/pipeline"}, Base: "/job/pipeline/3", ID: "3"}
+
+	_, err := run.Replay(context.Background(), "", map[string]string{"Script9": ""})
+	assert.Error(t, err)
+}
+
+const restartPage = `<html><body><form method="post" action="restart" name="restart">
+<select name="stageName"><option value="Build">Build</option><option>Deploy</option></select>
+</form></body></html>`
+
+func newRestartMock(building bool, page int) (*PipelineRun, *MockRequester) {
+	jenkins := newMockJenkins()
+	mock := jenkins.Requester.(*MockRequester)
+	mock.GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		*response.(*string) = restartPage
+		return &http.Response{StatusCode: page}, nil
+	}
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		response.(*BuildResponse).Building = building
+		return &http.Response{StatusCode: 200}, nil
+	}
+	run := &PipelineRun{Job: &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/pipeline"}, Base: "/job/pipeline/3", ID: "3"}
+	return run, mock
+}
+
+func TestPipelineRun_RestartableStages(t *testing.T) {
+	run, _ := newRestartMock(false, 200)
+
+	stages, err := run.RestartableStages(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Build", "Deploy"}, stages)
+}
+
+func TestPipelineRun_RestartFromStage_Errors(t *testing.T) {
+	var restartErr *ErrRestartFromStage
+
+	run, _ := newRestartMock(false, 404)
+	_, err := run.RestartFromStage(context.Background(), "Build")
+	require.ErrorAs(t, err, &restartErr)
+	assert.Equal(t, RestartNotDeclarative, restartErr.Reason)
+	assert.Equal(t, "Build", restartErr.Stage)
+
+	run, _ = newRestartMock(true, 200)
+	_, err = run.RestartFromStage(context.Background(), "Build")
+	require.ErrorAs(t, err, &restartErr)
+	assert.Equal(t, RestartStillRunning, restartErr.Reason)
+
+	run, _ = newRestartMock(false, 200)
+	_, err = run.RestartFromStage(context.Background(), "Test")
+	require.ErrorAs(t, err, &restartErr)
+	assert.Equal(t, RestartStageNotRestartable, restartErr.Reason)
+}
+
+func TestPipelineRun_RestartFromStage(t *testing.T) {
+	replayPollInterval = time.Millisecond
+	defer func() { replayPollInterval = time.Second }()
+
+	run, mock := newRestartMock(false, 200)
+	restarted := false
+	var form url.Values
+	mock.PostFunc = func(ctx context.Context, endpoint string, payload io.Reader, response interface{}, query map[string]string) (*http.Response, error) {
+		assert.Equal(t, "/job/pipeline/3/restart/restart", endpoint)
+		body, _ := io.ReadAll(payload)
+		form, _ = url.ParseQuery(string(body))
+		restarted = true
+		return &http.Response{StatusCode: 200}, nil
+	}
+	mock.GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		switch r := response.(type) {
+		case *JobResponse:
+			r.NextBuildNumber = 4
+			r.LastBuild.Number = 3
+			if restarted {
+				r.LastBuild.Number = 4
+			}
+		case *BuildResponse:
+			if strings.HasSuffix(endpoint, "/4") {
+				r.Number = 4
+				r.Actions = []generalObj{{Causes: []map[string]interface{}{
+					{"_class": restartCauseClass, "originRunNumber": float64(3), "originStage": "Deploy"},
+				}}}
+			}
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+
+	build, err := run.RestartFromStage(context.Background(), "Deploy")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), build.GetBuildNumber())
+	assert.JSONEq(t, `{"stageName":"Deploy"}`, form.Get("json"))
+}
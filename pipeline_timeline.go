@@ -0,0 +1,250 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Timeline lays out the stages, parallel branches and steps of a pipeline
+// run on tracks, for export to trace viewers and charts.
+type Timeline struct {
+	Run *FlowNode
+	// Tracks holds the stages and their steps first, then one track per
+	// parallel branch with the branch and its steps.
+	Tracks []*TimelineTrack
+}
+
+// TimelineTrack is a row of a Timeline. Its nodes do not overlap, except
+// for steps and nested stages which lie within their stage or branch.
+type TimelineTrack struct {
+	Name  string
+	Nodes []*FlowNode
+}
+
+// NewTimeline lays out the flow graph of a run, as returned by FlowGraph.
+func NewTimeline(run *FlowNode) *Timeline {
+	t := &Timeline{Run: run}
+	stages := &TimelineTrack{Name: "Stages"}
+	t.Tracks = append(t.Tracks, stages)
+	for _, stage := range run.Children {
+		t.addNode(stages, stage, stage.Name)
+	}
+	return t
+}
+
+// addNode puts the node, its steps and its nested stages on track, and its
+// branches on tracks of their own.
+func (t *Timeline) addNode(track *TimelineTrack, node *FlowNode, path string) {
+	track.Nodes = append(track.Nodes, node)
+	for _, c := range node.Children {
+		switch c.Kind {
+		case FlowStep:
+			track.Nodes = append(track.Nodes, c)
+		case FlowStage:
+			t.addNode(track, c, path+" / "+c.Name)
+		default:
+			branch := &TimelineTrack{Name: path + " / " + c.Name}
+			t.Tracks = append(t.Tracks, branch)
+			t.addNode(branch, c, branch.Name)
+		}
+	}
+}
+
+// Timeline returns the timeline of the run.
+func (pr *PipelineRun) Timeline(ctx context.Context) (*Timeline, error) {
+	graph, err := pr.FlowGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewTimeline(graph), nil
+}
+
+// CriticalPath returns the stages of the run, each followed by its nested
+// stages and by the parallel branch that finished last in it, which is the
+// one the stage waited for. Of nested stages running at the same time, only
+// the one that finished last is kept.
+func (t *Timeline) CriticalPath() []*FlowNode {
+	steps := t.criticalPath()
+	path := make([]*FlowNode, len(steps))
+	for i, s := range steps {
+		path[i] = s.node
+	}
+	return path
+}
+
+// criticalStep is a node of the critical path with the names of its
+// ancestors, e.g. "Test / integration".
+type criticalStep struct {
+	node *FlowNode
+	path string
+}
+
+func (t *Timeline) criticalPath() []criticalStep {
+	path := make([]criticalStep, 0, len(t.Run.Children))
+	for _, stage := range t.Run.Children {
+		path = appendCriticalPath(path, stage, stage.Name)
+	}
+	return path
+}
+
+func appendCriticalPath(path []criticalStep, node *FlowNode, name string) []criticalStep {
+	path = append(path, criticalStep{node: node, path: name})
+	var stages []*FlowNode
+	var last *FlowNode
+	for _, c := range node.Children {
+		switch c.Kind {
+		case FlowStage:
+			// of overlapping stages only the one that finished last counts
+			if n := len(stages); n > 0 && c.StartTime < flowEnd(stages[n-1]) {
+				if flowEnd(c) > flowEnd(stages[n-1]) {
+					stages[n-1] = c
+				}
+				continue
+			}
+			stages = append(stages, c)
+		case FlowBranch:
+			if last == nil || flowEnd(c) > flowEnd(last) {
+				last = c
+			}
+		}
+	}
+	for _, s := range stages {
+		path = appendCriticalPath(path, s, name+" / "+s.Name)
+	}
+	if last != nil {
+		path = appendCriticalPath(path, last, name+" / "+last.Name)
+	}
+	return path
+}
+
+// flowEnd returns the time the node finished.
+func flowEnd(n *FlowNode) int64 {
+	return n.StartTime + n.Duration
+}
+
+// traceEvent is an event of the Chrome Trace Event format. Times are in
+// microseconds.
+type traceEvent struct {
+	Name     string            `json:"name"`
+	Category string            `json:"cat,omitempty"`
+	Phase    string            `json:"ph"`
+	Time     int64             `json:"ts"`
+	Duration int64             `json:"dur,omitempty"`
+	Process  int               `json:"pid"`
+	Thread   int               `json:"tid"`
+	Args     map[string]string `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the timeline in the Chrome Trace Event format,
+// which Perfetto and chrome://tracing open. Every track is a thread, with
+// the steps nested in their stage or branch.
+func (t *Timeline) WriteChromeTrace(w io.Writer) error {
+	events := []traceEvent{{Name: "process_name", Phase: "M", Process: 1, Args: map[string]string{"name": t.Run.Name}}}
+	for i, track := range t.Tracks {
+		events = append(events, traceEvent{Name: "thread_name", Phase: "M", Process: 1, Thread: i + 1, Args: map[string]string{"name": track.Name}})
+		for _, n := range track.Nodes {
+			args := map[string]string{"id": n.ID, "status": n.Status}
+			if n.Description != "" {
+				args["description"] = n.Description
+			}
+			if n.Error != nil {
+				args["error"] = n.Error.Message
+			}
+			events = append(events, traceEvent{
+				Name:     n.Name,
+				Category: n.Kind,
+				Phase:    "X",
+				Time:     n.StartTime * 1000,
+				Duration: n.Duration * 1000,
+				Process:  1,
+				Thread:   i + 1,
+				Args:     args,
+			})
+		}
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{"traceEvents": events, "displayTimeUnit": "ms"})
+}
+
+// WriteMermaid writes the stages and branches of the timeline as a Mermaid
+// Gantt chart, one section per track. Steps are left out.
+func (t *Timeline) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	fmt.Fprintf(&b, "    title %s\n", mermaidText(t.Run.Name))
+	b.WriteString("    dateFormat x\n")
+	b.WriteString("    axisFormat %H:%M:%S\n")
+	for _, track := range t.Tracks {
+		fmt.Fprintf(&b, "    section %s\n", mermaidText(track.Name))
+		for _, n := range track.Nodes {
+			if n.Kind == FlowStep {
+				continue
+			}
+			tags := ""
+			switch {
+			case n.Failed():
+				tags = "crit, "
+			case isRunning(n.Status):
+				tags = "active, "
+			case n.Status == PipelineStatusSuccess:
+				tags = "done, "
+			}
+			fmt.Fprintf(&b, "    %s :%sn%s, %d, %d\n", mermaidText(n.Name), tags, n.ID, n.StartTime, n.StartTime+n.Duration)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidText removes the characters separating the fields of a Mermaid task.
+func mermaidText(s string) string {
+	return strings.NewReplacer(":", " ", ";", " ", "#", "", "\n", " ").Replace(s)
+}
+
+// WriteCriticalPathCSV writes the critical path of the timeline as CSV, with
+// start times relative to the start of the run and the share of the run
+// duration each stage or branch took.
+func (t *Timeline) WriteCriticalPathCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"path", "kind", "status", "start_ms", "duration_ms", "percent"}); err != nil {
+		return err
+	}
+	for _, step := range t.criticalPath() {
+		n := step.node
+		percent := 0.0
+		if t.Run.Duration > 0 {
+			percent = float64(n.Duration) * 100 / float64(t.Run.Duration)
+		}
+		record := []string{
+			step.path,
+			n.Kind,
+			n.Status,
+			strconv.FormatInt(n.StartTime-t.Run.StartTime, 10),
+			strconv.FormatInt(n.Duration, 10),
+			strconv.FormatFloat(percent, 'f', 1, 64),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
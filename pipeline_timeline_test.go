@@ -0,0 +1,162 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineRun_Timeline(t *testing.T) {
+	timeline, err := newFlowGraphRun(t).Timeline(context.Background())
+	require.NoError(t, err)
+
+	names := make([]string, len(timeline.Tracks))
+	for i, track := range timeline.Tracks {
+		names[i] = track.Name
+	}
+	assert.Equal(t, []string{"Stages", "Test / unit", "Test / integration"}, names)
+	assert.Equal(t, 4, len(timeline.Tracks[0].Nodes))
+
+	path := timeline.CriticalPath()
+	require.Equal(t, 3, len(path))
+	assert.Equal(t, "integration", path[2].Name)
+}
+
+func TestPipelineRun_Timeline_NestedStages(t *testing.T) {
+	timeline, err := newNestedFlowGraphRun(t).Timeline(context.Background())
+	require.NoError(t, err)
+
+	names := make([]string, len(timeline.Tracks))
+	for i, track := range timeline.Tracks {
+		names[i] = track.Name
+	}
+	assert.Equal(t, []string{"Stages", "Check / Unit / fast", "Check / Unit / slow"}, names)
+	stages := make([]string, 0)
+	for _, n := range timeline.Tracks[0].Nodes {
+		if n.Kind == FlowStage {
+			stages = append(stages, n.Name)
+		}
+	}
+	assert.Equal(t, []string{"Check", "Lint", "Unit", "Deploy"}, stages)
+
+	path := make([]string, 0)
+	for _, n := range timeline.CriticalPath() {
+		path = append(path, n.Name)
+	}
+	assert.Equal(t, []string{"Check", "Lint", "Unit", "slow", "Deploy"}, path)
+}
+
+func TestTimeline_WriteChromeTrace(t *testing.T) {
+	timeline, err := newFlowGraphRun(t).Timeline(context.Background())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, timeline.WriteChromeTrace(&buf))
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+
+	threads := make(map[int]string)
+	var integration *traceEvent
+	for i, e := range trace.TraceEvents {
+		if e.Name == "thread_name" {
+			threads[e.Thread] = e.Args["name"]
+		}
+		if e.Name == "integration" {
+			integration = &trace.TraceEvents[i]
+		}
+	}
+	assert.Equal(t, map[int]string{1: "Stages", 2: "Test / unit", 3: "Test / integration"}, threads)
+	require.NotNil(t, integration)
+	assert.Equal(t, "X", integration.Phase)
+	assert.Equal(t, 3, integration.Thread)
+	assert.Equal(t, int64(22000000), integration.Time)
+	assert.Equal(t, int64(39000000), integration.Duration)
+}
+
+func TestTimeline_WriteMermaid(t *testing.T) {
+	timeline, err := newFlowGraphRun(t).Timeline(context.Background())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, timeline.WriteMermaid(&buf))
+	assert.Equal(t, `gantt
+    title 7
+    dateFormat x
+    axisFormat %H:%M:%S
+    section Stages
+    Build :done, n6, 1000, 21000
+    Test :crit, n12, 21000, 61000
+    section Test / unit
+    unit :done, n15, 22000, 32000
+    section Test / integration
+    integration :crit, n16, 22000, 61000
+`, buf.String())
+}
+
+func TestTimeline_WriteCriticalPathCSV(t *testing.T) {
+	timeline, err := newFlowGraphRun(t).Timeline(context.Background())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, timeline.WriteCriticalPathCSV(&buf))
+	assert.Equal(t, `path,kind,status,start_ms,duration_ms,percent
+Build,stage,SUCCESS,0,20000,33.3
+Test,stage,FAILED,20000,40000,66.7
+Test / integration,parallel,FAILED,21000,39000,65.0
+`, buf.String())
+}
+
+func TestTimeline_WriteCriticalPathCSV_NestedStages(t *testing.T) {
+	timeline, err := newNestedFlowGraphRun(t).Timeline(context.Background())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, timeline.WriteCriticalPathCSV(&buf))
+	assert.Equal(t, `path,kind,status,start_ms,duration_ms,percent
+Check,stage,SUCCESS,0,40000,80.0
+Check / Lint,stage,SUCCESS,1000,5000,10.0
+Check / Unit,stage,SUCCESS,7000,30000,60.0
+Check / Unit / slow,parallel,SUCCESS,8000,28000,56.0
+Deploy,stage,SUCCESS,40000,9000,18.0
+`, buf.String())
+}
+
+func TestTimeline_CriticalPath_OverlappingStages(t *testing.T) {
+	stage := func(name string, start, duration int64, children ...*FlowNode) *FlowNode {
+		return &FlowNode{ID: name, Kind: FlowStage, Name: name, Status: PipelineStatusSuccess, StartTime: start, Duration: duration, Children: children}
+	}
+	run := &FlowNode{ID: "5", Kind: FlowRun, Name: "#5", StartTime: 0, Duration: 40000, Children: []*FlowNode{
+		stage("Check", 0, 40000,
+			stage("Fast", 1000, 10000),
+			stage("Slow", 1000, 30000),
+			stage("Report", 31000, 9000)),
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewTimeline(run).WriteCriticalPathCSV(&buf))
+	assert.Equal(t, `path,kind,status,start_ms,duration_ms,percent
+Check,stage,SUCCESS,0,40000,100.0
+Check / Slow,stage,SUCCESS,1000,30000,75.0
+Check / Report,stage,SUCCESS,31000,9000,22.5
+`, buf.String())
+}
@@ -0,0 +1,187 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// Type URIs of the provenance statement.
+const (
+	InTotoStatementType = "https://in-toto.io/Statement/v1"
+	SLSAProvenanceType  = "https://slsa.dev/provenance/v1"
+	// JenkinsBuildType tells verifiers how to read the build definition
+	// produced by Build.Provenance.
+	JenkinsBuildType = "https://github.com/bndr/gojenkins/provenance/jenkins-build@v1"
+)
+
+// ProvenanceStatement is an in-toto statement with a SLSA v1 provenance
+// predicate. It marshals to the JSON expected by signing tools.
+type ProvenanceStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     SLSAProvenance       `json:"predicate"`
+}
+
+// ResourceDescriptor identifies an artifact or a source of a build.
+type ResourceDescriptor struct {
+	Name        string                 `json:"name,omitempty"`
+	URI         string                 `json:"uri,omitempty"`
+	Digest      map[string]string      `json:"digest,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// SLSAProvenance is the SLSA v1 provenance predicate.
+type SLSAProvenance struct {
+	BuildDefinition ProvenanceBuildDefinition `json:"buildDefinition"`
+	RunDetails      ProvenanceRunDetails      `json:"runDetails"`
+}
+
+// ProvenanceBuildDefinition describes the inputs of the build.
+type ProvenanceBuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   map[string]interface{} `json:"externalParameters"`
+	InternalParameters   map[string]interface{} `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor   `json:"resolvedDependencies,omitempty"`
+}
+
+// ProvenanceRunDetails describes the controller that ran the build.
+type ProvenanceRunDetails struct {
+	Builder  ProvenanceBuilder  `json:"builder"`
+	Metadata ProvenanceMetadata `json:"metadata"`
+}
+
+// ProvenanceBuilder identifies the Jenkins controller.
+type ProvenanceBuilder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// ProvenanceMetadata holds the URL and times of the build.
+type ProvenanceMetadata struct {
+	InvocationID string     `json:"invocationId"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// WriteJSON writes the statement as indented JSON.
+func (p *ProvenanceStatement) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// Provenance builds a SLSA provenance statement for the build. Every
+// artifact is downloaded to compute its SHA-256, nothing is written to
+// disk. The sources are taken from the git actions, or from GetRevision
+// for other SCMs. The build should have been polled and be finished.
+func (b *Build) Provenance(ctx context.Context) (*ProvenanceStatement, error) {
+	subjects := make([]ResourceDescriptor, 0, len(b.Raw.Artifacts))
+	for _, a := range b.Raw.Artifacts {
+		digest, err := b.artifactSHA256(ctx, a.RelativePath)
+		if err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, ResourceDescriptor{Name: a.RelativePath, Digest: map[string]string{"sha256": digest}})
+	}
+
+	parameters := make(map[string]interface{})
+	for _, p := range b.GetParameters() {
+		parameters[p.Name] = p.Value
+	}
+	external := map[string]interface{}{"parameters": parameters}
+	if b.Job != nil {
+		external["job"] = strings.TrimPrefix(b.Job.Base, "/")
+	}
+
+	causes := make([]map[string]string, 0)
+	for _, a := range b.GetTypedActions() {
+		if ca, ok := a.(*CauseAction); ok {
+			for _, c := range ca.Causes {
+				causes = append(causes, map[string]string{"class": c.Class(), "description": c.Description()})
+			}
+		}
+	}
+
+	builder := ProvenanceBuilder{ID: b.Jenkins.Server}
+	if b.Jenkins.Version != "" {
+		builder.Version = map[string]string{"jenkins": b.Jenkins.Version}
+	}
+	metadata := ProvenanceMetadata{InvocationID: b.GetUrl()}
+	if metadata.InvocationID == "" {
+		metadata.InvocationID = b.Jenkins.Server + b.Base
+	}
+	if b.Raw.Timestamp > 0 {
+		started := b.GetTimestamp().UTC()
+		metadata.StartedOn = &started
+		if !b.Raw.Building {
+			finished := started.Add(time.Duration(b.GetDuration()) * time.Millisecond)
+			metadata.FinishedOn = &finished
+		}
+	}
+
+	return &ProvenanceStatement{
+		Type:          InTotoStatementType,
+		Subject:       subjects,
+		PredicateType: SLSAProvenanceType,
+		Predicate: SLSAProvenance{
+			BuildDefinition: ProvenanceBuildDefinition{
+				BuildType:            JenkinsBuildType,
+				ExternalParameters:   external,
+				InternalParameters:   map[string]interface{}{"causes": causes},
+				ResolvedDependencies: b.sourceDependencies(),
+			},
+			RunDetails: ProvenanceRunDetails{Builder: builder, Metadata: metadata},
+		},
+	}, nil
+}
+
+// sourceDependencies lists the revisions the build checked out.
+func (b *Build) sourceDependencies() []ResourceDescriptor {
+	deps := make([]ResourceDescriptor, 0)
+	for _, a := range b.GetTypedActions() {
+		git, ok := a.(*GitBuildDataAction)
+		if !ok || git.LastBuiltRevision.SHA1 == "" {
+			continue
+		}
+		var annotations map[string]interface{}
+		if branches := git.LastBuiltRevision.Branch; len(branches) > 0 && branches[0].Name != "" {
+			annotations = map[string]interface{}{"branch": branches[0].Name}
+		}
+		for _, remote := range git.RemoteUrls {
+			deps = append(deps, ResourceDescriptor{
+				URI:         "git+" + remote,
+				Digest:      map[string]string{"gitCommit": git.LastBuiltRevision.SHA1},
+				Annotations: annotations,
+			})
+		}
+	}
+	if len(deps) > 0 {
+		return deps
+	}
+	if revision := b.GetRevision(); revision != "" {
+		kind := b.Raw.ChangeSet.Kind
+		if kind == "" && len(b.Raw.ChangeSets) > 0 {
+			kind = b.Raw.ChangeSets[0].Kind
+		}
+		deps = append(deps, ResourceDescriptor{Name: kind, Digest: map[string]string{"revision": revision}})
+	}
+	return deps
+}
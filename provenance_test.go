@@ -0,0 +1,85 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const provenanceBuild = `{"number": 1, "url": "http://localhost:8080/job/app/1/", "timestamp": 1704067200000, "duration": 90000,
+	"artifacts": [{"fileName": "one.txt", "relativePath": "dist/one.txt"}],
+	"actions": [
+		{"_class": "hudson.model.CauseAction", "causes": [{"_class": "hudson.model.Cause$UserIdCause", "shortDescription": "Started by user alice", "userId": "alice"}]},
+		{"_class": "hudson.model.ParametersAction", "parameters": [{"name": "ENV", "value": "prod"}]},
+		{"_class": "hudson.plugins.git.util.BuildData", "lastBuiltRevision": {"SHA1": "abc123", "branch": [{"SHA1": "abc123", "name": "origin/main"}]},
+			"remoteUrls": ["https://example.com/repo.git"]}
+	]}`
+
+func TestBuild_Provenance(t *testing.T) {
+	build, _ := newArtifactBuild(map[string]string{"dist/one.txt": "one"})
+	require.NoError(t, json.Unmarshal([]byte(provenanceBuild), build.Raw))
+	build.Jenkins.Version = "2.440.1"
+	build.Job = &Job{Jenkins: build.Jenkins, Base: "/job/app"}
+
+	statement, err := build.Provenance(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceDescriptor{{
+		Name:   "dist/one.txt",
+		Digest: map[string]string{"sha256": "7692c3ad3540bb803c020b3aee66cd8887123234ea0c6e7143c0add73ff431ed"},
+	}}, statement.Subject)
+
+	def := statement.Predicate.BuildDefinition
+	assert.Equal(t, "job/app", def.ExternalParameters["job"])
+	assert.Equal(t, map[string]interface{}{"ENV": "prod"}, def.ExternalParameters["parameters"])
+	assert.Equal(t, []map[string]string{{"class": "hudson.model.Cause$UserIdCause", "description": "Started by user alice"}}, def.InternalParameters["causes"])
+	assert.Equal(t, []ResourceDescriptor{{
+		URI:         "git+https://example.com/repo.git",
+		Digest:      map[string]string{"gitCommit": "abc123"},
+		Annotations: map[string]interface{}{"branch": "origin/main"},
+	}}, def.ResolvedDependencies)
+
+	run := statement.Predicate.RunDetails
+	assert.Equal(t, ProvenanceBuilder{ID: "http://localhost:8080", Version: map[string]string{"jenkins": "2.440.1"}}, run.Builder)
+	assert.Equal(t, "http://localhost:8080/job/app/1/", run.Metadata.InvocationID)
+	assert.Equal(t, 90*time.Second, run.Metadata.FinishedOn.Sub(*run.Metadata.StartedOn))
+
+	var buf bytes.Buffer
+	require.NoError(t, statement.WriteJSON(&buf))
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, InTotoStatementType, decoded["_type"])
+	assert.Equal(t, SLSAProvenanceType, decoded["predicateType"])
+	assert.Contains(t, buf.String(), `"startedOn": "2024-01-01T00:00:00Z"`)
+}
+
+func TestBuild_Provenance_OtherSCM(t *testing.T) {
+	build := &Build{Jenkins: newMockJenkins(), Raw: &BuildResponse{ChangeSet: BuildChangeSet{Kind: "svn", Revisions: []struct {
+		Module   string
+		Revision int
+	}{{Module: "trunk", Revision: 42}}}}, Base: "/job/app/2"}
+
+	statement, err := build.Provenance(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceDescriptor{{Name: "svn", Digest: map[string]string{"revision": "42"}}}, statement.Predicate.BuildDefinition.ResolvedDependencies)
+	assert.Equal(t, "http://localhost:8080/job/app/2", statement.Predicate.RunDetails.Metadata.InvocationID)
+	assert.Nil(t, statement.Predicate.RunDetails.Metadata.StartedOn)
+}
@@ -41,6 +41,7 @@ type Task struct {
 type taskResponse struct {
 	Actions                    []generalAction `json:"actions"`
 	Blocked                    bool            `json:"blocked"`
+	Cancelled                  bool            `json:"cancelled"`
 	Buildable                  bool            `json:"buildable"`
 	BuildableStartMilliseconds int64           `json:"buildableStartMilliseconds"`
 	ID                         int64           `json:"id"`
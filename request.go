@@ -95,6 +95,10 @@ func (r *Requester) Post(ctx context.Context, endpoint string, payload io.Reader
 	}
 	ar.SetHeader("Content-Type", "application/x-www-form-urlencoded")
 	ar.Suffix = ""
+	if raw, ok := responseStruct.(*string); ok {
+		// hand raw bodies to Do as is, like Get does
+		return r.Do(ctx, ar, raw, querystring)
+	}
 	return r.Do(ctx, ar, &responseStruct, querystring)
 }
 
@@ -119,6 +123,18 @@ func (r *Requester) PostXML(ctx context.Context, endpoint string, xml string, re
 	return r.Do(ctx, ar, &responseStruct, querystring)
 }
 
+// PostJSONBody sends a POST request with payload as a JSON body, to APIs
+// such as Blue Ocean which take JSON rather than form data.
+func (r *Requester) PostJSONBody(ctx context.Context, endpoint string, payload io.Reader, responseStruct interface{}) (*http.Response, error) {
+	ar := NewAPIRequest("POST", endpoint, payload)
+	if err := r.SetCrumb(ctx, ar); err != nil {
+		return nil, err
+	}
+	ar.SetHeader("Content-Type", "application/json")
+	ar.Suffix = ""
+	return r.Do(ctx, ar, responseStruct)
+}
+
 // GetJSON sends a GET request and expects a JSON response.
 func (r *Requester) GetJSON(ctx context.Context, endpoint string, responseStruct interface{}, query map[string]string) (*http.Response, error) {
 	ar := NewAPIRequest("GET", endpoint, nil)
@@ -142,6 +158,17 @@ func (r *Requester) Get(ctx context.Context, endpoint string, responseStruct int
 	return r.Do(ctx, ar, responseStruct, querystring)
 }
 
+// GetRange sends a GET request for the bytes of endpoint from offset on.
+// The response body is left unread; the caller must close it. Servers that
+// ignore the range answer with 200 and the whole content instead of 206.
+func (r *Requester) GetRange(ctx context.Context, endpoint string, offset int64) (*http.Response, error) {
+	ar := NewAPIRequest("GET", endpoint, nil)
+	ar.SetHeader("Range", fmt.Sprintf("bytes=%d-", offset))
+	ar.Suffix = ""
+	var body io.ReadCloser
+	return r.Do(ctx, ar, &body)
+}
+
 // SetClient sets the HTTP client to use for requests.
 func (r *Requester) SetClient(client *http.Client) *Requester {
 	r.Client = client
@@ -250,9 +277,15 @@ func (r *Requester) Do(ctx context.Context, ar *APIRequest, responseStruct inter
 		if errorText != "" {
 			return nil, errors.New(errorText)
 		}
-		switch responseStruct.(type) {
+		switch v := responseStruct.(type) {
 		case *string:
 			return r.ReadRawResponse(response, responseStruct)
+		case *io.ReadCloser:
+			// the caller reads and closes the body
+			*v = response.Body
+			return response, nil
+		case io.Writer:
+			return r.ReadStreamResponse(response, v)
 		default:
 			return r.ReadJSONResponse(response, responseStruct)
 		}
@@ -278,6 +311,21 @@ func (r *Requester) ReadRawResponse(response *http.Response, responseStruct inte
 	return response, nil
 }
 
+// ReadStreamResponse copies the response body into w without buffering it.
+// Error responses (status 400 and above) are discarded instead of being written.
+func (r *Requester) ReadStreamResponse(response *http.Response, w io.Writer) (*http.Response, error) {
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= 400 {
+		_, _ = io.Copy(io.Discard, response.Body)
+		return response, nil
+	}
+	if _, err := io.Copy(w, response.Body); err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
 // ReadJSONResponse reads the response body as JSON and decodes it into responseStruct.
 func (r *Requester) ReadJSONResponse(response *http.Response, responseStruct interface{}) (*http.Response, error) {
 	defer func() { _ = response.Body.Close() }()
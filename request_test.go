@@ -16,8 +16,10 @@ package gojenkins
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -136,6 +138,79 @@ func TestReadRawResponse_InvalidType(t *testing.T) {
 	assert.Contains(t, err.Error(), "could not cast responseStruct to *string")
 }
 
+func TestReadStreamResponse_Success(t *testing.T) {
+	requester := &Requester{}
+
+	response := &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString("streamed content")),
+	}
+
+	var buf bytes.Buffer
+	resp, err := requester.ReadStreamResponse(response, &buf)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, resp)
+	assert.Equal(t, "streamed content", buf.String())
+}
+
+func TestReadStreamResponse_ErrorStatus(t *testing.T) {
+	requester := &Requester{}
+
+	response := &http.Response{
+		StatusCode: 404,
+		Body:       io.NopCloser(bytes.NewBufferString("<html>not found</html>")),
+	}
+
+	var buf bytes.Buffer
+	resp, err := requester.ReadStreamResponse(response, &buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestRequester_GetRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/artifact/app.jar/", r.URL.Path)
+		assert.Equal(t, "bytes=4-", r.Header.Get("Range"))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.WriteString(w, "tail")
+	}))
+	defer server.Close()
+	requester := &Requester{Base: server.URL, Client: server.Client()}
+
+	resp, err := requester.GetRange(context.Background(), "/artifact/app.jar", 4)
+	assert.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "tail", string(body))
+}
+
+func TestRequester_PostJSONBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/crumbIssuer/") {
+			_, _ = io.WriteString(w, `{"crumbRequestField": "Jenkins-Crumb", "crumb": "c0ffee"}`)
+			return
+		}
+		assert.Equal(t, "/blue/rest/input/", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "c0ffee", r.Header.Get("Jenkins-Crumb"))
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"id": "ok"}`, string(body))
+		_, _ = io.WriteString(w, `{"state": "RUNNING"}`)
+	}))
+	defer server.Close()
+	requester := &Requester{Base: server.URL, Client: server.Client()}
+
+	var result map[string]string
+	resp, err := requester.PostJSONBody(context.Background(), "/blue/rest/input/", bytes.NewBufferString(`{"id": "ok"}`), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "RUNNING", result["state"])
+}
+
 func TestReadJSONResponse_Success(t *testing.T) {
 	requester := &Requester{}
 
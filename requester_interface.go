@@ -32,5 +32,21 @@ type JenkinsRequester interface {
 	GetXML(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error)
 }
 
-// Ensure Requester implements JenkinsRequester
-var _ JenkinsRequester = (*Requester)(nil)
+// RangeRequester is implemented by requesters that can resume downloads
+// with HTTP Range requests.
+type RangeRequester interface {
+	GetRange(ctx context.Context, endpoint string, offset int64) (*http.Response, error)
+}
+
+// JSONBodyRequester is implemented by requesters that can post a JSON body
+// as is, with the crumb of regular POST requests.
+type JSONBodyRequester interface {
+	PostJSONBody(ctx context.Context, endpoint string, payload io.Reader, response interface{}) (*http.Response, error)
+}
+
+// Ensure Requester implements JenkinsRequester, RangeRequester and JSONBodyRequester
+var (
+	_ JenkinsRequester  = (*Requester)(nil)
+	_ RangeRequester    = (*Requester)(nil)
+	_ JSONBodyRequester = (*Requester)(nil)
+)
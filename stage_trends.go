@@ -0,0 +1,242 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// number of runs described at once by StageTrends
+const stageTrendsConcurrency = 4
+
+// StageDuration is the duration of a stage in one run, in milliseconds.
+type StageDuration struct {
+	RunID    string `json:"runId"`
+	Status   string `json:"status"`
+	Duration int64  `json:"durationMillis"`
+}
+
+// StageTrend holds the statistics of a stage over the runs it finished in.
+// Durations are in milliseconds.
+type StageTrend struct {
+	Name        string  `json:"name"`
+	Count       int     `json:"count"`
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failureRate"`
+	Min         int64   `json:"minMillis"`
+	Median      int64   `json:"medianMillis"`
+	P95         int64   `json:"p95Millis"`
+	Max         int64   `json:"maxMillis"`
+	// Runs holds the stage in every run, oldest first.
+	Runs []StageDuration `json:"runs"`
+}
+
+// StageTrends lists the stages of a job in the order of its latest run.
+type StageTrends []StageTrend
+
+// StageRegression is a stage whose median duration grew between the older
+// and the newer half of the runs.
+type StageRegression struct {
+	Name   string `json:"name"`
+	Before int64  `json:"beforeMillis"`
+	After  int64  `json:"afterMillis"`
+	// Change is the relative growth of the median, 0.5 for 50% slower.
+	Change float64 `json:"change"`
+}
+
+// Regressions returns the stages whose median duration in the newer half of
+// their runs is more than threshold above the one of the older half, e.g.
+// 0.25 for 25% slower. Stages with fewer than 4 runs are left out.
+func (t StageTrends) Regressions(threshold float64) []StageRegression {
+	regressions := make([]StageRegression, 0)
+	for _, s := range t {
+		if len(s.Runs) < 4 {
+			continue
+		}
+		half := len(s.Runs) / 2
+		before := percentile(sortedDurations(s.Runs[:half]), 50)
+		after := percentile(sortedDurations(s.Runs[half:]), 50)
+		if before <= 0 {
+			continue
+		}
+		change := float64(after-before) / float64(before)
+		if change > threshold {
+			regressions = append(regressions, StageRegression{Name: s.Name, Before: before, After: after, Change: change})
+		}
+	}
+	return regressions
+}
+
+// StageTrends aggregates the duration and outcome of every stage over the
+// last n runs of a pipeline job. Stages are matched by name, with the
+// "Branch: " prefix of parallel branches removed. Stages that are running
+// or were skipped are not counted.
+func (j *Job) StageTrends(ctx context.Context, n int) (StageTrends, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("number of runs must be at least 1, got %d", n)
+	}
+	runs, err := j.recentPipelineRuns(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	return stageTrends(runs), nil
+}
+
+// recentPipelineRuns returns the last n runs, newest first. wfapi only lists
+// the last few runs, so older ones are described one by one.
+func (j *Job) recentPipelineRuns(ctx context.Context, n int) ([]PipelineRun, error) {
+	runs, err := j.GetPipelineRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) < n {
+		var resp struct {
+			Builds []struct {
+				Number int64 `json:"number"`
+			} `json:"allBuilds"`
+		}
+		tree := fmt.Sprintf("allBuilds[number]{0,%d}", n)
+		if _, err := j.Jenkins.Requester.GetJSON(ctx, j.Base, &resp, map[string]string{"tree": tree}); err != nil {
+			return nil, err
+		}
+		listed := make(map[string]bool, len(runs))
+		for _, r := range runs {
+			listed[r.ID] = true
+		}
+		missing := make([]string, 0, len(resp.Builds))
+		for _, b := range resp.Builds {
+			if id := strconv.FormatInt(b.Number, 10); !listed[id] {
+				missing = append(missing, id)
+			}
+		}
+		older, err := j.describePipelineRuns(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, older...)
+	}
+	sort.SliceStable(runs, func(a, b int) bool { return runs[a].StartTime > runs[b].StartTime })
+	if len(runs) > n {
+		runs = runs[:n]
+	}
+	return runs, nil
+}
+
+// describePipelineRuns fetches the runs with the given IDs concurrently.
+func (j *Job) describePipelineRuns(ctx context.Context, ids []string) ([]PipelineRun, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	runs := make([]PipelineRun, len(ids))
+	errs := make([]error, len(ids))
+	sem := make(chan struct{}, stageTrendsConcurrency)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			run, err := j.GetPipelineRun(ctx, ids[i])
+			if err != nil {
+				errs[i] = fmt.Errorf("run %s: %w", ids[i], err)
+				cancel()
+				return
+			}
+			runs[i] = *run
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return runs, nil
+}
+
+// stageTrends aggregates runs given newest first.
+func stageTrends(runs []PipelineRun) StageTrends {
+	var names []string
+	byName := make(map[string]*StageTrend)
+	for _, run := range runs {
+		for _, stage := range run.Stages {
+			name := stageName(stage.Name)
+			if _, ok := byName[name]; !ok {
+				byName[name] = &StageTrend{Name: name, Runs: make([]StageDuration, 0)}
+				names = append(names, name)
+			}
+		}
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, stage := range runs[i].Stages {
+			if isRunning(stage.Status) || stage.Status == PipelineStatusNotExecuted {
+				continue
+			}
+			s := byName[stageName(stage.Name)]
+			s.Runs = append(s.Runs, StageDuration{RunID: runs[i].ID, Status: stage.Status, Duration: stage.Duration})
+			if stage.Status == PipelineStatusFailed {
+				s.Failures++
+			}
+		}
+	}
+
+	trends := make(StageTrends, 0, len(names))
+	for _, name := range names {
+		s := byName[name]
+		s.Count = len(s.Runs)
+		if s.Count > 0 {
+			durations := sortedDurations(s.Runs)
+			s.FailureRate = float64(s.Failures) / float64(s.Count)
+			s.Min = durations[0]
+			s.Median = percentile(durations, 50)
+			s.P95 = percentile(durations, 95)
+			s.Max = durations[len(durations)-1]
+		}
+		trends = append(trends, *s)
+	}
+	return trends
+}
+
+// stageName normalizes the name of a stage or parallel branch.
+func stageName(name string) string {
+	return strings.TrimSpace(strings.TrimPrefix(name, parallelBranchPrefix))
+}
+
+func sortedDurations(runs []StageDuration) []int64 {
+	durations := make([]int64, len(runs))
+	for i, r := range runs {
+		durations[i] = r.Duration
+	}
+	sort.Slice(durations, func(a, b int) bool { return durations[a] < durations[b] })
+	return durations
+}
+
+// percentile interpolates the p-th percentile of sorted durations.
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	frac := rank - float64(lower)
+	return int64(math.Round(float64(sorted[lower]) + frac*float64(sorted[upper]-sorted[lower])))
+}
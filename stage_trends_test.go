@@ -0,0 +1,111 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stageTrendRun returns run id with a Build stage of the given duration and
+// a parallel branch "unit".
+func stageTrendRun(id int, build int64, buildStatus, unitStatus string) PipelineRun {
+	return PipelineRun{
+		ID:        strconv.Itoa(id),
+		StartTime: int64(id) * 1000,
+		Stages: []PipelineNode{
+			{Name: "Build", Status: buildStatus, Duration: build},
+			{Name: "Branch: unit", Status: unitStatus, Duration: 50},
+		},
+	}
+}
+
+func TestJob_StageTrends(t *testing.T) {
+	runs := map[int]PipelineRun{
+		1: stageTrendRun(1, 100, "SUCCESS", "SUCCESS"),
+		2: stageTrendRun(2, 120, "SUCCESS", "NOT_EXECUTED"),
+		3: stageTrendRun(3, 110, "SUCCESS", "SUCCESS"),
+		4: stageTrendRun(4, 300, "SUCCESS", "SUCCESS"),
+		5: stageTrendRun(5, 310, "FAILED", "SUCCESS"),
+		6: stageTrendRun(6, 290, "SUCCESS", "IN_PROGRESS"),
+	}
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		switch endpoint {
+		case "/job/app/wfapi/runs":
+			// wfapi only lists the last runs
+			*response.(*[]PipelineRun) = []PipelineRun{runs[6], runs[5], runs[4]}
+		case "/job/app":
+			assert.Equal(t, "allBuilds[number]{0,6}", query["tree"])
+			builds := `{"allBuilds": [{"number": 6}, {"number": 5}, {"number": 4}, {"number": 3}, {"number": 2}, {"number": 1}]}`
+			require.NoError(t, json.Unmarshal([]byte(builds), response))
+		case "/job/app/1/wfapi/describe", "/job/app/2/wfapi/describe", "/job/app/3/wfapi/describe":
+			id, _ := strconv.Atoi(endpoint[len("/job/app/") : len("/job/app/")+1])
+			*response.(*PipelineRun) = runs[id]
+		default:
+			return &http.Response{StatusCode: 404}, assert.AnError
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	job := &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/app"}
+
+	trends, err := job.StageTrends(context.Background(), 6)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(trends))
+
+	build := trends[0]
+	assert.Equal(t, "Build", build.Name)
+	assert.Equal(t, 6, build.Count)
+	assert.Equal(t, 1, build.Failures)
+	assert.InDelta(t, 1.0/6, build.FailureRate, 1e-9)
+	assert.Equal(t, int64(100), build.Min)
+	assert.Equal(t, int64(205), build.Median)
+	assert.Equal(t, int64(308), build.P95)
+	assert.Equal(t, int64(310), build.Max)
+	assert.Equal(t, "1", build.Runs[0].RunID)
+
+	// skipped and running stages are not counted
+	unit := trends[1]
+	assert.Equal(t, "unit", unit.Name)
+	assert.Equal(t, 4, unit.Count)
+
+	regressions := trends.Regressions(0.5)
+	require.Equal(t, 1, len(regressions))
+	assert.Equal(t, "Build", regressions[0].Name)
+	assert.Equal(t, int64(110), regressions[0].Before)
+	assert.Equal(t, int64(300), regressions[0].After)
+	assert.Empty(t, trends.Regressions(2))
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, int64(0), percentile(nil, 50))
+	assert.Equal(t, int64(7), percentile([]int64{7}, 95))
+	assert.Equal(t, int64(15), percentile([]int64{10, 20}, 50))
+	assert.Equal(t, int64(40), percentile([]int64{10, 20, 30, 40}, 100))
+}
+
+func TestJob_StageTrends_InvalidCount(t *testing.T) {
+	job := &Job{Jenkins: newMockJenkins(), Raw: &JobResponse{}, Base: "/job/app"}
+	for _, n := range []int{0, -1} {
+		_, err := job.StageTrends(context.Background(), n)
+		assert.EqualError(t, err, "number of runs must be at least 1, got "+strconv.Itoa(n))
+	}
+}
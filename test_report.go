@@ -0,0 +1,314 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Test case statuses reported by the JUnit plugin.
+const (
+	TestStatusPassed     = "PASSED"
+	TestStatusFixed      = "FIXED"
+	TestStatusFailed     = "FAILED"
+	TestStatusRegression = "REGRESSION"
+	TestStatusSkipped    = "SKIPPED"
+)
+
+// TestResult represents the test results of a build.
+type TestResult struct {
+	Duration  float64     `json:"duration"`
+	Empty     bool        `json:"empty"`
+	FailCount int64       `json:"failCount"`
+	PassCount int64       `json:"passCount"`
+	SkipCount int64       `json:"skipCount"`
+	Suites    []TestSuite `json:"suites"`
+}
+
+// TestSuite is a JUnit test suite of a test report.
+type TestSuite struct {
+	Cases     []TestCase `json:"cases"`
+	Duration  float64    `json:"duration"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Stderr    string     `json:"stderr"`
+	Stdout    string     `json:"stdout"`
+	Timestamp string     `json:"timestamp"`
+}
+
+// TestCase is a single test of a test report.
+type TestCase struct {
+	Age             int64   `json:"age"`
+	ClassName       string  `json:"className"`
+	Duration        float64 `json:"duration"`
+	ErrorDetails    string  `json:"errorDetails"`
+	ErrorStackTrace string  `json:"errorStackTrace"`
+	FailedSince     int64   `json:"failedSince"`
+	Name            string  `json:"name"`
+	Skipped         bool    `json:"skipped"`
+	SkippedMessage  string  `json:"skippedMessage"`
+	Status          string  `json:"status"`
+	Stderr          string  `json:"stderr"`
+	Stdout          string  `json:"stdout"`
+
+	build *Build
+}
+
+// link points every case at the build the report belongs to.
+func (r *TestResult) link(b *Build) {
+	for i := range r.Suites {
+		for j := range r.Suites[i].Cases {
+			r.Suites[i].Cases[j].build = b
+		}
+	}
+}
+
+// Cases returns all test cases of the report.
+func (r *TestResult) Cases() []*TestCase {
+	cases := make([]*TestCase, 0)
+	for i := range r.Suites {
+		for j := range r.Suites[i].Cases {
+			cases = append(cases, &r.Suites[i].Cases[j])
+		}
+	}
+	return cases
+}
+
+// FullName returns the class name and name of the test, which identify it
+// across builds.
+func (c *TestCase) FullName() string {
+	if c.ClassName == "" {
+		return c.Name
+	}
+	return c.ClassName + "." + c.Name
+}
+
+// IsFailed returns true if the test failed.
+func (c *TestCase) IsFailed() bool {
+	return c.Status == TestStatusFailed || c.Status == TestStatusRegression
+}
+
+// IsSkipped returns true if the test was skipped.
+func (c *TestCase) IsSkipped() bool {
+	return c.Skipped || c.Status == TestStatusSkipped
+}
+
+// safeTestName replaces the characters the JUnit plugin does not allow in URLs.
+func safeTestName(s string) string {
+	return strings.NewReplacer("/", "_", `\`, "_", ":", "_", "?", "_", "#", "_", "%", "_", "<", "_", ">", "_").Replace(s)
+}
+
+// path returns the path of the case below testReport/.
+func (c *TestCase) path() string {
+	pkg, class := "(root)", c.ClassName
+	if i := strings.LastIndex(c.ClassName, "."); i >= 0 {
+		pkg, class = c.ClassName[:i], c.ClassName[i+1:]
+	}
+	segments := []string{pkg, class, c.Name}
+	for i, s := range segments {
+		segments[i] = url.PathEscape(safeTestName(s))
+	}
+	return strings.Join(segments, "/")
+}
+
+// URL returns the absolute URL of the test case page.
+func (c *TestCase) URL() string {
+	if c.build == nil {
+		return ""
+	}
+	return c.build.Jenkins.Server + c.build.Base + "/testReport/" + c.path() + "/"
+}
+
+// testReport returns the test report of the build, or nil if it has none.
+func (b *Build) testReport(ctx context.Context) (*TestResult, error) {
+	report := new(TestResult)
+	resp, err := b.Jenkins.Requester.GetJSON(ctx, b.Base+"/testReport", report, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	report.link(b)
+	return report, nil
+}
+
+// TestCaseRun is the outcome of a test case in a single build.
+type TestCaseRun struct {
+	Build        int64
+	Status       string
+	Duration     float64
+	ErrorDetails string
+}
+
+// History returns the outcome of the test in this build and up to n-1
+// builds before it, newest first. Builds that did not run the test, or
+// have been deleted, are left out.
+func (c *TestCase) History(ctx context.Context, n int) ([]TestCaseRun, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("number of builds must not be negative, got %d", n)
+	}
+	if c.build == nil || c.build.Job == nil {
+		return nil, errors.New("test case is not linked to a build of a job")
+	}
+	runs := make([]TestCaseRun, 0, n)
+	number := c.build.GetBuildNumber()
+	for i := int64(0); i < int64(n) && number-i > 0; i++ {
+		var tc TestCase
+		endpoint := c.build.Job.Base + "/" + strconv.FormatInt(number-i, 10) + "/testReport/" + c.path()
+		resp, err := c.build.Jenkins.Requester.GetJSON(ctx, endpoint, &tc, nil)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, TestCaseRun{Build: number - i, Status: tc.Status, Duration: tc.Duration, ErrorDetails: tc.ErrorDetails})
+	}
+	return runs, nil
+}
+
+// TestComparison lists how the tests of a build changed against a baseline.
+type TestComparison struct {
+	NewFailures  []*TestCase
+	Fixed        []*TestCase
+	StillFailing []*TestCase
+	NewlySkipped []*TestCase
+}
+
+// CompareTests compares the test report of the build with the one of other,
+// usually an earlier build. Tests that did not exist in other count as new.
+func (b *Build) CompareTests(ctx context.Context, other *Build) (*TestComparison, error) {
+	current, err := b.GetResultSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := other.GetResultSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return CompareTestResults(baseline, current), nil
+}
+
+// CompareTestResults compares two test reports.
+func CompareTestResults(baseline, current *TestResult) *TestComparison {
+	before := make(map[string]*TestCase)
+	for _, c := range baseline.Cases() {
+		before[c.FullName()] = c
+	}
+	cmp := &TestComparison{
+		NewFailures:  make([]*TestCase, 0),
+		Fixed:        make([]*TestCase, 0),
+		StillFailing: make([]*TestCase, 0),
+		NewlySkipped: make([]*TestCase, 0),
+	}
+	for _, c := range current.Cases() {
+		prev, existed := before[c.FullName()]
+		switch {
+		case c.IsFailed() && existed && prev.IsFailed():
+			cmp.StillFailing = append(cmp.StillFailing, c)
+		case c.IsFailed():
+			cmp.NewFailures = append(cmp.NewFailures, c)
+		case c.IsSkipped() && (!existed || !prev.IsSkipped()):
+			cmp.NewlySkipped = append(cmp.NewlySkipped, c)
+		case existed && prev.IsFailed():
+			cmp.Fixed = append(cmp.Fixed, c)
+		}
+	}
+	return cmp
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	ID        string          `xml:"id,attr,omitempty"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+	Stdout    string          `xml:"system-out,omitempty"`
+	Stderr    string          `xml:"system-err,omitempty"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+	Stdout    string        `xml:"system-out,omitempty"`
+	Stderr    string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnitXML writes the report as a JUnit XML document, so it can be
+// uploaded to other systems.
+func (r *TestResult) WriteJUnitXML(w io.Writer) error {
+	doc := junitTestSuites{Time: r.Duration}
+	for _, s := range r.Suites {
+		suite := junitTestSuite{Name: s.Name, ID: s.ID, Time: s.Duration, Timestamp: s.Timestamp, Stdout: s.Stdout, Stderr: s.Stderr}
+		for i := range s.Cases {
+			c := &s.Cases[i]
+			tc := junitTestCase{ClassName: c.ClassName, Name: c.Name, Time: c.Duration, Stdout: c.Stdout, Stderr: c.Stderr}
+			switch {
+			case c.IsFailed():
+				tc.Failure = &junitMessage{Message: c.ErrorDetails, Text: c.ErrorStackTrace}
+				suite.Failures++
+			case c.IsSkipped():
+				tc.Skipped = &junitMessage{Message: c.SkippedMessage}
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Tests = len(s.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
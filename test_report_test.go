@@ -0,0 +1,122 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	baselineReport = `{"duration": 1.5, "failCount": 2, "passCount": 2, "skipCount": 0, "suites": [{"name": "app.LoginTest", "duration": 1.5, "id": null, "cases": [
+		{"className": "app.LoginTest", "name": "testLogin", "status": "PASSED"},
+		{"className": "app.LoginTest", "name": "testLogout", "status": "FAILED", "errorDetails": "boom"},
+		{"className": "app.LoginTest", "name": "testTimeout", "status": "FAILED"},
+		{"className": "app.LoginTest", "name": "testSlow", "status": "PASSED"}
+	]}]}`
+	currentReport = `{"duration": 2, "failCount": 2, "passCount": 1, "skipCount": 1, "suites": [{"name": "app.LoginTest", "duration": 2, "cases": [
+		{"className": "app.LoginTest", "name": "testLogin", "status": "REGRESSION", "errorDetails": "expected <200>", "errorStackTrace": "at LoginTest.java:12", "duration": 0.5},
+		{"className": "app.LoginTest", "name": "testLogout", "status": "FIXED"},
+		{"className": "app.LoginTest", "name": "testTimeout", "status": "FAILED", "age": 2, "failedSince": 8},
+		{"className": "app.LoginTest", "name": "testSlow", "status": "SKIPPED", "skipped": true, "skippedMessage": "too slow"}
+	]}]}`
+)
+
+func newTestReportMock(t *testing.T, endpoints map[string]string) *Job {
+	jenkins := newMockJenkins()
+	jenkins.Server = "http://jenkins.local"
+	jenkins.Requester.(*MockRequester).GetJSONFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		data, ok := endpoints[endpoint]
+		if !ok {
+			return &http.Response{StatusCode: 404}, assert.AnError
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), response))
+		return &http.Response{StatusCode: 200}, nil
+	}
+	return &Job{Jenkins: jenkins, Raw: &JobResponse{}, Base: "/job/app"}
+}
+
+func TestBuild_CompareTests(t *testing.T) {
+	job := newTestReportMock(t, map[string]string{
+		"/job/app/9/testReport":  baselineReport,
+		"/job/app/10/testReport": currentReport,
+	})
+	current := &Build{Jenkins: job.Jenkins, Job: job, Raw: &BuildResponse{Number: 10}, Base: "/job/app/10"}
+	baseline := &Build{Jenkins: job.Jenkins, Job: job, Raw: &BuildResponse{Number: 9}, Base: "/job/app/9"}
+
+	cmp, err := current.CompareTests(context.Background(), baseline)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(cmp.NewFailures))
+	assert.Equal(t, "app.LoginTest.testLogin", cmp.NewFailures[0].FullName())
+	assert.Equal(t, "http://jenkins.local/job/app/10/testReport/app/LoginTest/testLogin/", cmp.NewFailures[0].URL())
+	require.Equal(t, 1, len(cmp.Fixed))
+	assert.Equal(t, "testLogout", cmp.Fixed[0].Name)
+	require.Equal(t, 1, len(cmp.StillFailing))
+	assert.Equal(t, int64(8), cmp.StillFailing[0].FailedSince)
+	require.Equal(t, 1, len(cmp.NewlySkipped))
+	assert.Equal(t, "too slow", cmp.NewlySkipped[0].SkippedMessage)
+}
+
+func TestTestCase_History(t *testing.T) {
+	job := newTestReportMock(t, map[string]string{
+		"/job/app/10/testReport":                           currentReport,
+		"/job/app/10/testReport/app/LoginTest/testTimeout": `{"status": "FAILED", "duration": 3}`,
+		"/job/app/8/testReport/app/LoginTest/testTimeout":  `{"status": "PASSED", "duration": 1}`,
+	})
+	build := &Build{Jenkins: job.Jenkins, Job: job, Raw: &BuildResponse{Number: 10}, Base: "/job/app/10"}
+
+	report, err := build.GetResultSet(context.Background())
+	require.NoError(t, err)
+	history, err := report.Suites[0].Cases[2].History(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []TestCaseRun{
+		{Build: 10, Status: TestStatusFailed, Duration: 3},
+		{Build: 8, Status: TestStatusPassed, Duration: 1},
+	}, history)
+}
+
+func TestTestCase_History_NegativeCount(t *testing.T) {
+	// used to panic in make
+	_, err := (&TestCase{}).History(context.Background(), -1)
+	assert.EqualError(t, err, "number of builds must not be negative, got -1")
+}
+
+func TestTestCase_URLEscaping(t *testing.T) {
+	c := &TestCase{ClassName: "Root", Name: "with space/and:colon"}
+	assert.Equal(t, "%28root%29/Root/with%20space_and_colon", c.path())
+}
+
+func TestTestResult_WriteJUnitXML(t *testing.T) {
+	var report TestResult
+	require.NoError(t, json.Unmarshal([]byte(currentReport), &report))
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteJUnitXML(&buf))
+	assert.Contains(t, buf.String(), `<testsuites tests="4" failures="2" skipped="1" time="2">`)
+	assert.Contains(t, buf.String(), `<failure message="expected &lt;200&gt;">at LoginTest.java:12</failure>`)
+
+	var parsed junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	require.Equal(t, 1, len(parsed.Suites))
+	assert.Equal(t, 4, len(parsed.Suites[0].Cases))
+	assert.Equal(t, "too slow", parsed.Suites[0].Cases[3].Skipped.Message)
+}
@@ -14,7 +14,16 @@
 
 package gojenkins
 
-import "encoding/json"
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
 
 func makeJson(data interface{}) string {
 	str, err := json.Marshal(data)
@@ -23,3 +32,76 @@ func makeJson(data interface{}) string {
 	}
 	return string(json.RawMessage(str))
 }
+
+// safeJoin joins a slash separated relative path from the server onto dir,
+// refusing absolute paths and paths that would escape dir.
+func safeJoin(dir string, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("refusing absolute path %q", name)
+	}
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("refusing path %q outside of target directory", name)
+	}
+	target := filepath.Join(dir, filepath.FromSlash(clean))
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing path %q outside of target directory", name)
+	}
+	return target, nil
+}
+
+// extractZip extracts every regular file of a zip archive below dir, after
+// dropping the first strip path components of each entry. When keep is not
+// nil only the entries it accepts, by their stripped name, are extracted.
+// Symlinks and entries escaping dir are rejected. Returns the extracted paths.
+func extractZip(zr *zip.Reader, dir string, strip int, keep func(name string) bool) ([]string, error) {
+	extracted := make([]string, 0, len(zr.File))
+	for _, f := range zr.File {
+		if f.Mode()&os.ModeSymlink != 0 {
+			return extracted, fmt.Errorf("refusing symlink %q in archive", f.Name)
+		}
+		name := strings.ReplaceAll(f.Name, "\\", "/")
+		if strip > 0 {
+			parts := strings.SplitN(strings.TrimPrefix(name, "/"), "/", strip+1)
+			if len(parts) <= strip {
+				continue
+			}
+			name = parts[strip]
+		}
+		if name == "" || strings.HasSuffix(name, "/") || (keep != nil && !keep(name)) {
+			continue
+		}
+		target, err := safeJoin(dir, name)
+		if err != nil {
+			return extracted, err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return extracted, err
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return extracted, err
+		}
+		extracted = append(extracted, target)
+	}
+	return extracted, nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -93,6 +93,28 @@ func (v *View) GetUrl() string {
 	return v.Raw.URL
 }
 
+// GetConfig retrieves the view's XML configuration.
+func (v *View) GetConfig(ctx context.Context) (string, error) {
+	var data string
+	_, err := v.Jenkins.Requester.GetXML(ctx, v.Base+"/config.xml", &data, nil)
+	if err != nil {
+		return "", err
+	}
+	return data, nil
+}
+
+// UpdateConfig updates the view's XML configuration.
+func (v *View) UpdateConfig(ctx context.Context, config string) error {
+	resp, err := v.Jenkins.Requester.PostXML(ctx, v.Base+"/config.xml", config, nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == 200 {
+		return nil
+	}
+	return errors.New(strconv.Itoa(resp.StatusCode))
+}
+
 // Poll fetches the latest view data from Jenkins.
 func (v *View) Poll(ctx context.Context) (int, error) {
 	response, err := v.Jenkins.Requester.GetJSON(ctx, v.Base, v.Raw, nil)
@@ -0,0 +1,196 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Workspace gives access to the workspace of a job through the ws/ endpoint.
+// Pipeline jobs have no job level workspace; their workspaces live on the
+// flow nodes of each run.
+type Workspace struct {
+	Job     *Job
+	Jenkins *Jenkins
+	Base    string
+}
+
+// WorkspaceEntry is a file or directory in a workspace.
+// Path is relative to the workspace root and uses forward slashes.
+type WorkspaceEntry struct {
+	Name     string
+	Path     string
+	IsDir    bool
+	Children []*WorkspaceEntry
+}
+
+// Workspace returns the workspace of the job.
+// It fails when the job has no workspace, e.g. before its first build.
+func (j *Job) Workspace(ctx context.Context) (*Workspace, error) {
+	ws := &Workspace{Job: j, Jenkins: j.Jenkins, Base: j.Base + "/ws"}
+	if _, err := ws.List(ctx, ""); err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// endpoint returns the URL path of a file or directory in the workspace.
+func (w *Workspace) endpoint(p string) string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return w.Base
+	}
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return w.Base + "/" + strings.Join(segments, "/")
+}
+
+// List returns the entries of a directory in the workspace, "" being the root.
+func (w *Workspace) List(ctx context.Context, dir string) ([]*WorkspaceEntry, error) {
+	var listing string
+	resp, err := w.Jenkins.Requester.Get(ctx, w.endpoint(dir)+"/*plain*", &listing, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing workspace directory %q: status %d", dir, resp.StatusCode)
+	}
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	entries := make([]*WorkspaceEntry, 0)
+	for _, line := range strings.Split(listing, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		entry := &WorkspaceEntry{Name: strings.TrimSuffix(line, "/"), IsDir: strings.HasSuffix(line, "/")}
+		entry.Path = joinFullName(dir, entry.Name)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Tree returns dir and everything below it, listing every directory recursively.
+func (w *Workspace) Tree(ctx context.Context, dir string) (*WorkspaceEntry, error) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	root := &WorkspaceEntry{Name: path.Base("/" + dir), Path: dir, IsDir: true}
+	if err := w.fill(ctx, root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (w *Workspace) fill(ctx context.Context, dir *WorkspaceEntry) error {
+	children, err := w.List(ctx, dir.Path)
+	if err != nil {
+		return err
+	}
+	dir.Children = children
+	for _, child := range children {
+		if child.IsDir {
+			if err := w.fill(ctx, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Download streams a single workspace file into dst.
+func (w *Workspace) Download(ctx context.Context, file string, dst io.Writer) error {
+	resp, err := w.Jenkins.Requester.Get(ctx, w.endpoint(file), dst, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading workspace file %q: status %d", file, resp.StatusCode)
+	}
+	return nil
+}
+
+// DownloadZip streams a zip archive of a workspace directory into dst.
+// Entries in the archive are prefixed with the name of the directory.
+func (w *Workspace) DownloadZip(ctx context.Context, dir string, dst io.Writer) error {
+	name := path.Base("/" + strings.Trim(dir, "/"))
+	if name == "/" {
+		name = "workspace"
+	}
+	resp, err := w.Jenkins.Requester.Get(ctx, w.endpoint(dir)+"/*zip*/"+url.PathEscape(name)+".zip", dst, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading workspace directory %q: status %d", dir, resp.StatusCode)
+	}
+	return nil
+}
+
+// Extract downloads a workspace directory as a single zip and extracts its
+// contents into localDir, which is created if needed. Entries that would
+// land outside of localDir, and symlinks, are rejected.
+// Returns the paths of the extracted files.
+func (w *Workspace) Extract(ctx context.Context, dir string, localDir string) ([]string, error) {
+	tmp, err := os.CreateTemp("", "gojenkins-ws-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if err := w.DownloadZip(ctx, dir, tmp); err != nil {
+		return nil, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		return nil, err
+	}
+	// drop the directory name Jenkins puts in front of every entry
+	return extractZip(zr, localDir, 1, nil)
+}
+
+// Wipe deletes the workspace of the job.
+func (w *Workspace) Wipe(ctx context.Context) error {
+	if w.Job == nil {
+		return errors.New("workspace has no job")
+	}
+	resp, err := w.Jenkins.Requester.Post(ctx, w.Job.Base+"/doWipeOutWorkspace", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2015 Vadim Kravcenko
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package gojenkins
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWorkspaceMock(files map[string]string) *Job {
+	jenkins := newMockJenkins()
+	jenkins.Requester.(*MockRequester).GetFunc = func(ctx context.Context, endpoint string, response interface{}, query map[string]string) (*http.Response, error) {
+		content, ok := files[endpoint]
+		if !ok {
+			return &http.Response{StatusCode: 404}, nil
+		}
+		switch v := response.(type) {
+		case *string:
+			*v = content
+		case io.Writer:
+			_, _ = io.WriteString(v, content)
+		}
+		return &http.Response{StatusCode: 200}, nil
+	}
+	return &Job{Jenkins: jenkins, Raw: &JobResponse{Name: "app"}, Base: "/job/app"}
+}
+
+func TestWorkspace_ListAndTree(t *testing.T) {
+	job := newWorkspaceMock(map[string]string{
+		"/job/app/ws/*plain*":                "src/\nREADME.md\n",
+		"/job/app/ws/src/*plain*":            "main.go\nmy%20dir/\n",
+		"/job/app/ws/src/my%2520dir/*plain*": "",
+	})
+
+	ws, err := job.Workspace(context.Background())
+	require.NoError(t, err)
+
+	entries, err := ws.List(context.Background(), "")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(entries))
+	assert.Equal(t, "src", entries[0].Path)
+	assert.True(t, entries[0].IsDir)
+	assert.False(t, entries[1].IsDir)
+
+	tree, err := ws.Tree(context.Background(), "")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tree.Children))
+	assert.Equal(t, "src/main.go", tree.Children[0].Children[0].Path)
+}
+
+func TestWorkspace_NoWorkspace(t *testing.T) {
+	job := newWorkspaceMock(map[string]string{})
+
+	ws, err := job.Workspace(context.Background())
+	assert.Error(t, err)
+	assert.Nil(t, ws)
+}
+
+func TestWorkspace_Download(t *testing.T) {
+	job := newWorkspaceMock(map[string]string{"/job/app/ws/out/report%20final.txt": "all good"})
+	ws := &Workspace{Job: job, Jenkins: job.Jenkins, Base: "/job/app/ws"}
+
+	var buf bytes.Buffer
+	err := ws.Download(context.Background(), "out/report final.txt", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "all good", buf.String())
+
+	// ".." never leaves the workspace on the server side
+	assert.Equal(t, "/job/app/ws/etc/passwd", ws.endpoint("../../etc/passwd"))
+}
+
+func buildZip(t *testing.T, entries map[string]string) string {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.String()
+}
+
+func TestWorkspace_Extract(t *testing.T) {
+	archive := buildZip(t, map[string]string{
+		"src/main.go":     "package main",
+		"src/pkg/util.go": "package pkg",
+	})
+	job := newWorkspaceMock(map[string]string{"/job/app/ws/src/*zip*/src.zip": archive})
+	ws := &Workspace{Job: job, Jenkins: job.Jenkins, Base: "/job/app/ws"}
+
+	dir := t.TempDir()
+	files, err := ws.Extract(context.Background(), "src", dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+
+	data, err := os.ReadFile(filepath.Join(dir, "pkg", "util.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg", string(data))
+}
+
+func TestWorkspace_ExtractRejectsTraversal(t *testing.T) {
+	archive := buildZip(t, map[string]string{"ws/../../evil.sh": "rm -rf /"})
+	job := newWorkspaceMock(map[string]string{"/job/app/ws/*zip*/workspace.zip": archive})
+	ws := &Workspace{Job: job, Jenkins: job.Jenkins, Base: "/job/app/ws"}
+
+	dir := t.TempDir()
+	_, err := ws.Extract(context.Background(), "", filepath.Join(dir, "out"))
+	assert.Error(t, err)
+	_, statErr := os.Stat(filepath.Join(dir, "evil.sh"))
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestSafeJoin(t *testing.T) {
+	dir := t.TempDir()
+	for _, bad := range []string{"/etc/passwd", "../x", "a/../../x", `..\x`} {
+		_, err := safeJoin(dir, bad)
+		assert.Error(t, err, bad)
+	}
+	p, err := safeJoin(dir, "a/./b/../c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "a", "c.txt"), p)
+}
+
+func TestWorkspace_Wipe(t *testing.T) {
+	job := newWorkspaceMock(nil)
+	ws := &Workspace{Job: job, Jenkins: job.Jenkins, Base: "/job/app/ws"}
+
+	err := ws.Wipe(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "/job/app/doWipeOutWorkspace", job.Jenkins.Requester.(*MockRequester).lastEndpoint)
+}